@@ -0,0 +1,265 @@
+package forja
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+const generatedHeader = "// AUTOGENERATED, DO NOT EDIT\n"
+
+// clientDirs are the directories of WriteTsClientDir holding a file per
+// package or namespace.
+var clientDirs = []string{"types", "client"}
+
+// WriteTsClientDir writes the client to dir split in several files, so that
+// bundlers can tree-shake the handlers a frontend does not use, and reviews of
+// large APIs stay readable:
+//
+//   - types/<pkg>.ts, the types of every package, importing the ones of
+//     other packages they use
+//   - client/<namespace>.ts, the handlers of every top-level namespace,
+//     exported as <Namespace>NamespaceClient
+//   - runtime.ts, the code shared by all handlers
+//   - index.ts, exporting all of the above along with createApiClient
+//
+// Files are written like WriteTsClient, and the generated files of packages
+// and namespaces that no longer exist are removed.
+func (fj *Forja) WriteTsClientDir(dir string) error {
+	return writeClientFiles(dir, fj.generateClientFiles())
+}
+
+// writeClientFiles writes the files generated by generateClientFiles to dir.
+func writeClientFiles(dir string, files map[string]string) error {
+	for _, sub := range clientDirs {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return fmt.Errorf("failed to write TypeScript client to %s: %w", dir, err)
+		}
+	}
+
+	for _, name := range sortedKeys(files) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := writeFileIfChanged(path, []byte(files[name])); err != nil {
+			return fmt.Errorf("failed to write TypeScript client to %s: %w", path, err)
+		}
+	}
+
+	stale, err := staleClientFiles(dir, files)
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// staleClientFiles returns the files generated by WriteTsClientDir in dir
+// that are not part of files anymore. Files not starting with the generated
+// header were written by someone else and are kept.
+func staleClientFiles(dir string, files map[string]string) ([]string, error) {
+	var stale []string
+	for _, sub := range clientDirs {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := sub + "/" + entry.Name()
+			if _, ok := files[name]; ok || entry.IsDir() || !strings.HasSuffix(name, ".ts") {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(dir, sub, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(string(content), generatedHeader) {
+				stale = append(stale, name)
+			}
+		}
+	}
+	return stale, nil
+}
+
+// generateClientFiles returns the files of WriteTsClientDir by slash
+// separated path.
+func (fj *Forja) generateClientFiles() map[string]string {
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+
+	fj.typegen.reset()
+	handlers := fj.clientHandlers()
+	for _, typ := range fj.customTypes {
+		fj.typegen.FillTypeDefinitions(typ)
+	}
+
+	files := make(map[string]string)
+	addFile := func(name string, imports []string, body string) {
+		content := generatedHeader + strings.Join(imports, "") + "\n" + body
+		files[name] = formatTypescript(content, fj.config.Format)
+	}
+
+	// Types, by package
+	types := orderedmap.New[string, *strings.Builder]()
+	for pair := fj.typegen.typeDefs.Oldest(); pair != nil; pair = pair.Next() {
+		pkg := fj.typegen.typePackages[pair.Key]
+		body, exists := types.Get(pkg)
+		if !exists {
+			body = new(strings.Builder)
+			types.Set(pkg, body)
+		}
+		fmt.Fprintln(body, pair.Value)
+	}
+	var packages []string
+	for pair := types.Oldest(); pair != nil; pair = pair.Next() {
+		pkg, body := pair.Key, pair.Value.String()
+		packages = append(packages, pkg)
+		addFile("types/"+pkg+".ts", fj.typegen.typeImports(body, pkg, "./"), body)
+	}
+	sort.Strings(packages)
+
+	// Handlers, by top-level namespace
+	clients := orderedmap.New[string, []*clientHandler]()
+	for _, handler := range handlers {
+		top, _, _ := strings.Cut(handler.namespace, ".")
+		namespaceHandlers, _ := clients.Get(top)
+		clients.Set(top, append(namespaceHandlers, handler))
+	}
+
+	index := new(strings.Builder)
+	indexImports := []string{
+		"import { createDoFetch } from './runtime'\n",
+		"import type { ApiClientConfig } from './runtime'\n",
+	}
+	apiClient := newNamespaceTree()
+	apiClientMethods := newNamespaceTree()
+	for pair := clients.Oldest(); pair != nil; pair = pair.Next() {
+		top, namespaceHandlers := pair.Key, pair.Value
+		// Suffixed so that an api namespace does not clash with ApiClient
+		clientName := camelcaseNames(top, "NamespaceClient")
+
+		body := new(strings.Builder)
+		definitions := newNamespaceTree()
+		methods := newNamespaceTree()
+		for _, handler := range namespaceHandlers {
+			namespace := strings.TrimPrefix(strings.TrimPrefix(handler.namespace, top), ".")
+			fmt.Fprintf(body, "type %s = %s\n", handler.typeName, handler.typeDecl)
+			definitions.add(namespace, handler.name, handler.typeName, handler.doc)
+			methods.add(namespace, handler.name, handler.call, "")
+		}
+		fmt.Fprintf(body, "\nexport type %s = {\n", clientName)
+		definitions.write(body, "  ")
+		fmt.Fprintf(body, "}\n\nexport function create%s(doFetch: DoFetch): %s {\n  return {\n", clientName, clientName)
+		methods.write(body, "    ")
+		body.WriteString("  }\n}\n")
+
+		imports := []string{"import type { ApiResponse, DoFetch } from '../runtime'\n"}
+		if strings.Contains(body.String(), "transform(") {
+			imports = append(imports, "import { transform } from '../runtime'\n")
+		}
+		imports = append(imports, fj.typegen.typeImports(body.String(), "", "../types/")...)
+		addFile("client/"+top+".ts", imports, body.String())
+
+		indexImports = append(indexImports,
+			fmt.Sprintf("import { create%s } from './client/%s'\n", clientName, top),
+			fmt.Sprintf("import type { %s } from './client/%s'\n", clientName, top))
+		apiClient.add("", top, clientName, "")
+		apiClientMethods.add("", top, fmt.Sprintf("create%s(doFetch)", clientName), "")
+	}
+
+	// Runtime
+	runtime := new(strings.Builder)
+	runtime.WriteString(apiResponseTypes)
+	fmt.Fprintf(runtime, "\nexport const SCHEMA_HASH = '%s'\n", fj.schemaHash())
+	runtime.WriteString(apiClientConfig)
+	runtime.WriteString(`
+export type DoFetch = (
+  path: string,
+  params?: unknown,
+  decode?: (data: unknown) => unknown
+) => Promise<ApiResponse<any>>
+
+export function createDoFetch(
+  baseUrl: string,
+  config?: ApiClientConfig
+): DoFetch {
+`)
+	runtime.WriteString(doFetchFunction)
+	runtime.WriteString("  return doFetch\n}\n")
+	if fj.typegen.codecsUsed {
+		fj.typegen.printCodecs(runtime, true)
+	}
+	if fj.typegen.bytesUsed {
+		runtime.WriteString(base64Helpers)
+	}
+	addFile("runtime.ts", nil, runtime.String())
+
+	// Index
+	index.WriteString("export * from './runtime'\n")
+	for _, pkg := range packages {
+		fmt.Fprintf(index, "export * from './types/%s'\n", pkg)
+	}
+	for pair := clients.Oldest(); pair != nil; pair = pair.Next() {
+		fmt.Fprintf(index, "export * from './client/%s'\n", pair.Key)
+	}
+	index.WriteString("\n")
+	fj.printVariables(index)
+	index.WriteString("export type ApiClient = {\n")
+	apiClient.write(index, "  ")
+	index.WriteString(`}
+
+export function createApiClient(
+  baseUrl: string,
+  config?: ApiClientConfig
+): ApiClient {
+  const doFetch = createDoFetch(baseUrl, config)
+  return {
+`)
+	apiClientMethods.write(index, "    ")
+	index.WriteString("  }\n}\n")
+	addFile("index.ts", indexImports, index.String())
+
+	return files
+}
+
+// typeImports returns the imports of the generated types used by code, which
+// belongs to package pkg, from the types directory at dir.
+func (tp *typegen) typeImports(code, pkg, dir string) []string {
+	used := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, tok := range tokenize(code) {
+		if tok.kind != tokWord || seen[tok.text] {
+			continue
+		}
+		seen[tok.text] = true
+		if other, ok := tp.typePackages[tok.text]; ok && other != pkg {
+			used[other] = append(used[other], tok.text)
+		}
+	}
+
+	var imports []string
+	for _, other := range sortedKeys(used) {
+		names := used[other]
+		sort.Strings(names)
+		imports = append(imports, fmt.Sprintf("import type { %s } from '%s%s'\n", strings.Join(names, ", "), dir, other))
+	}
+	return imports
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,44 @@
+package forja
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	importedNames = regexp.MustCompile(`(?m)^import (?:type )?\{ ([^}]*) \}`)
+	declaredNames = regexp.MustCompile(`(?m)^export (?:type|function|const) (\w+)`)
+)
+
+func TestGenerateClientFilesApiNamespace(t *testing.T) {
+	fj := NewForja(echo.New())
+	AddHandlerWith(fj, watchGet, Namespace("api"))
+	AddHandlerWith(fj, watchCount, Namespace("api.stats"))
+
+	files := fj.generateClientFiles()
+	client, ok := files["client/api.ts"]
+	if !ok {
+		t.Fatalf("no client/api.ts generated, got files %v", sortedKeys(files))
+	}
+	for _, name := range []string{"ApiNamespaceClient", "createApiNamespaceClient"} {
+		if !regexp.MustCompile(`(?m)^export (?:type|function) ` + name + `\b`).MatchString(client) {
+			t.Errorf("client/api.ts does not export %s:\n%s", name, client)
+		}
+	}
+
+	index := files["index.ts"]
+	imported := make(map[string]bool)
+	for _, match := range importedNames.FindAllStringSubmatch(index, -1) {
+		for _, name := range strings.Split(match[1], ",") {
+			imported[strings.TrimSpace(name)] = true
+		}
+	}
+	for _, match := range declaredNames.FindAllStringSubmatch(index, -1) {
+		if imported[match[1]] {
+			t.Errorf("index.ts imports and declares %s:\n%s", match[1], index)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Command forja generates the typescript client of an application without
+// running it.
+//
+//	forja gen -register github.com/acme/app/api.Register -out web/src/api.ts
+//	forja gen -register ./api.Register -out web/src/api.ts -check
+//
+// The registration function must be exported from a package other than main,
+// and have the signature
+//
+//	func Register(router forja.Router) *forja.Forja
+//
+// so that the application calls it with its echo.Echo, and forja gen with a
+// router that discards routes. forja gen builds a program calling it in the
+// module of the current directory, which runs forja.RunGenerator with the
+// remaining flags, see its documentation for the available outputs.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const usage = `usage: forja gen -register <package>.<Func> [-out client.ts] [-outdir client/] [-schema schema.json] [-openapi openapi.json] [-check]`
+
+const program = `// Code generated by forja gen. DO NOT EDIT.
+
+package main
+
+import (
+	"github.com/alarbada/forja"
+
+	app %q
+)
+
+func main() {
+	forja.RunGenerator(app.%s)
+}
+`
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "gen" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	register, args, err := parseArgs(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "forja gen: %s\n%s\n", err, usage)
+		os.Exit(2)
+	}
+
+	if err := gen(register, args); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The generator already reported why
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "forja gen: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+// parseArgs extracts the -register flag from args, and returns the other ones
+// to be passed to the generator.
+func parseArgs(args []string) (register string, rest []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "register" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return "", nil, fmt.Errorf("missing value of -register")
+			}
+			i++
+			value = args[i]
+		}
+		register = value
+	}
+	if register == "" {
+		return "", nil, fmt.Errorf("missing -register")
+	}
+	return register, rest, nil
+}
+
+// gen runs the generator program for the given registration function, such
+// as github.com/acme/app/api.Register or ./api.Register
+func gen(register string, args []string) error {
+	dot := strings.LastIndex(register, ".")
+	slash := strings.LastIndex(register, "/")
+	if dot == -1 || dot < slash {
+		return fmt.Errorf("invalid -register %q, expected <package>.<Func>", register)
+	}
+	pkg, funcName := register[:dot], register[dot+1:]
+
+	if strings.HasPrefix(pkg, ".") {
+		importPath, err := goCommand("list", "-f", "{{.ImportPath}}", pkg)
+		if err != nil {
+			return err
+		}
+		pkg = importPath
+	}
+
+	goMod, err := goCommand("env", "GOMOD")
+	if err != nil {
+		return err
+	}
+	if goMod == "" || goMod == os.DevNull {
+		return fmt.Errorf("forja gen must be run inside a go module")
+	}
+
+	// The program is added to the module with an overlay, so that it uses its
+	// dependencies without writing anything to it
+	tmp, err := os.MkdirTemp("", "forja-gen")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	source := filepath.Join(tmp, "main.go")
+	if err := os.WriteFile(source, []byte(fmt.Sprintf(program, pkg, funcName)), 0644); err != nil {
+		return err
+	}
+	programDir := filepath.Join(filepath.Dir(goMod), "_forjagen")
+	overlay := filepath.Join(tmp, "overlay.json")
+	overlayJSON := fmt.Sprintf(`{"Replace": {%q: %q}}`, filepath.Join(programDir, "main.go"), source)
+	if err := os.WriteFile(overlay, []byte(overlayJSON), 0644); err != nil {
+		return err
+	}
+
+	// Built before running it, so that build errors are not confused with
+	// the exit status of -check
+	binary := filepath.Join(tmp, "forja-gen")
+	if _, err := goCommand("build", "-overlay", overlay, "-o", binary, programDir); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func goCommand(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
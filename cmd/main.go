@@ -1,7 +1,10 @@
 package main
 
 import (
-	"os/exec"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/alarbada/forja"
@@ -24,6 +27,7 @@ type ExampleParams struct {
 
 type ExampleResponse struct {
 	Greeting string `json:"greeting"`
+	Message  string `json:"message" forja:"deprecated=use greeting"`
 }
 
 func ExampleHandler1(c echo.Context, params ExampleParams) (*ExampleResponse, error) {
@@ -32,6 +36,9 @@ func ExampleHandler1(c echo.Context, params ExampleParams) (*ExampleResponse, er
 	return &ExampleResponse{Greeting: "Hello, " + params.Name}, nil
 }
 
+// ExampleHandler2 greets the user like ExampleHandler1.
+//
+// Deprecated: use ExampleHandler1 instead.
 func ExampleHandler2(c echo.Context, params ExampleParams) (*ExampleResponse, error) {
 	dump.P(params)
 
@@ -52,10 +59,12 @@ func HelloWorld(c echo.Context, params struct{}) (*HelloWorldOutput, error) {
 	}, nil
 }
 
+// Playlist is an ordered list of songs.
 type Playlist struct {
-	ID          string `json:"id,omitempty"`
-	PlaylistID  string `json:"playlistId,omitempty"`
-	Title       string `json:"title,omitempty"`
+	ID         string `json:"id,omitempty"`
+	PlaylistID string `json:"playlistId,omitempty"`
+	Title      string `json:"title,omitempty"`
+	// Pinned playlists are listed first
 	Pinned      bool   `json:"pinned,omitempty"`
 	Description string `json:"description,omitempty"`
 }
@@ -64,6 +73,15 @@ func getPlaylists(c echo.Context, _ struct{}) ([]Playlist, error) {
 	return nil, nil
 }
 
+type Page[T any] struct {
+	Items []T `json:"items"`
+	Total int `json:"total"`
+}
+
+func getPlaylistsPage(c echo.Context, _ struct{}) (*Page[Playlist], error) {
+	return &Page[Playlist]{}, nil
+}
+
 type Server struct{}
 
 func (s Server) theHandler(c echo.Context, input struct{}) (*struct{}, error) {
@@ -136,15 +154,137 @@ func weAlsoHandleEnums(c echo.Context, input EnumLike) (_ *weAlsoHandleEnumsResu
 	return &res, nil
 }
 
+type PlaylistStatus string
+
+const (
+	PlaylistStatusActive   PlaylistStatus = "active"
+	PlaylistStatusArchived PlaylistStatus = "archived"
+)
+
+type Visibility int
+
+const (
+	VisibilityPrivate Visibility = iota
+	VisibilityPublic
+)
+
+// Values makes Visibility an enum without having to register it with
+// forja.AddEnum
+func (Visibility) Values() []Visibility {
+	return []Visibility{VisibilityPrivate, VisibilityPublic}
+}
+
+type setPlaylistStatusInput struct {
+	PlaylistID string         `json:"playlistId"`
+	Status     PlaylistStatus `json:"status"`
+	Visibility Visibility     `json:"visibility"`
+}
+
+// Unknown statuses are rejected by forja before the handler is called.
+func setPlaylistStatus(c echo.Context, input setPlaylistStatusInput) (*struct{}, error) {
+	return &struct{}{}, nil
+}
+
+// PlaylistItem is one of the shapes that can be added to a playlist
+type PlaylistItem interface {
+	isPlaylistItem()
+}
+
+type Song struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+}
+
+type Podcast struct {
+	Title   string `json:"title"`
+	Episode int    `json:"episode"`
+}
+
+func (Song) isPlaylistItem()    {}
+func (Podcast) isPlaylistItem() {}
+
+type getPlaylistItemsOutput struct {
+	Items []forja.Union[PlaylistItem] `json:"items"`
+}
+
+func getPlaylistItems(c echo.Context, _ struct{}) (*getPlaylistItemsOutput, error) {
+	return &getPlaylistItemsOutput{
+		Items: []forja.Union[PlaylistItem]{
+			{Value: Song{Title: "Blue in Green", Artist: "Miles Davis"}},
+			{Value: Podcast{Title: "Go Time", Episode: 1}},
+		},
+	}, nil
+}
+
+type getPlaylistInput struct {
+	ID string `json:"id"`
+}
+
+type updatePlaylistInput struct {
+	ID          string              `json:"id"`
+	Title       forja.Patch[string] `json:"title"`
+	Description forja.Patch[string] `json:"description"`
+}
+
+// Fields not sent are left untouched, and fields sent as null are cleared.
+func updatePlaylist(c echo.Context, input updatePlaylistInput) (*Playlist, error) {
+	playlist := Playlist{ID: input.ID, Title: "My Favorites"}
+	if err := forja.ApplyPatch(&playlist, input); err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+// PlaylistService handlers are registered at once with forja.AddService
+type PlaylistService struct {
+	playlists []Playlist
+}
+
+func (s *PlaylistService) List(c echo.Context, _ struct{}) ([]Playlist, error) {
+	return s.playlists, nil
+}
+
+func (s *PlaylistService) Get(c echo.Context, input getPlaylistInput) (*Playlist, error) {
+	for _, playlist := range s.playlists {
+		if playlist.ID == input.ID {
+			return &playlist, nil
+		}
+	}
+	return nil, fmt.Errorf("playlist %s not found", input.ID)
+}
+
+// requireKey rejects requests without the given bearer key.
+func requireKey(key string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(auth), []byte(key)) != 1 {
+				return echo.ErrUnauthorized
+			}
+			return next(c)
+		}
+	}
+}
+
 func main() {
 	e := echo.New()
-	fj := forja.NewForja(e)
+	fj := forja.NewForjaWithConfig(e, forja.Config{
+		// Emit the doc comments of handlers, types and fields as JSDoc
+		Docs: true,
+		// Format the client like the scripts/ typescript
+		Format: forja.FormatOptions{Indent: "    "},
+	})
 
 	forja.AddHandler(fj, ExampleHandler1)
-	forja.AddHandler(fj, ExampleHandler2)
+	// Deprecated handlers send the Deprecation and Sunset headers
+	forja.AddHandlerWith(fj, ExampleHandler2,
+		forja.Deprecated(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), "use ExampleHandler1",
+			time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)))
 	forja.AddHandler(fj, HelloWorld)
 	forja.AddHandler(fj, pkg.SomeHandler)
 	forja.AddHandler(fj, getPlaylists)
+	forja.AddHandler(fj, getPlaylistsPage)
+	forja.AddHandler(fj, updatePlaylist)
 	forja.AddHandler(fj, ExampleWithExternalTypes)
 
 	server := Server{}
@@ -154,6 +294,21 @@ func main() {
 	forja.AddHandler(fj, weHandleInputPointers)
 	forja.AddHandler(fj, weAlsoHandleEnums)
 
+	forja.AddEnum(fj, PlaylistStatusActive, PlaylistStatusArchived)
+	forja.AddHandler(fj, setPlaylistStatus)
+
+	forja.AddUnion[PlaylistItem](fj, "kind", Song{}, Podcast{})
+	forja.AddHandler(fj, getPlaylistItems)
+
+	// Closures have no meaningful name, so it has to be set explicitly
+	forja.AddHandlerWith(fj, func(c echo.Context, input getPlaylistInput) (*Playlist, error) {
+		return &Playlist{ID: input.ID, Title: "My Favorites"}, nil
+	}, forja.Name("playlists.get"), forja.Namespace("admin"))
+
+	forja.AddService(fj, &PlaylistService{
+		playlists: []Playlist{{ID: "1", Title: "My Favorites"}},
+	})
+
 	// Add custom variables to be exported in the TypeScript client
 
 	// Simple primitive values
@@ -198,8 +353,19 @@ func main() {
 		},
 	})
 
-	cmd := exec.Command("prettier", "-w", "scripts/apiclient.ts")
-	fj.WriteTsClientWithCommand("scripts/apiclient.ts", cmd)
+	// Keep the client up to date while the server runs, it is only written
+	// when it changes
+	stop, err := fj.WatchTsClient("scripts/apiclient.ts")
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	defer stop()
+
+	// Let the frontend pull the client from /_forja/client.ts, only with the
+	// key set in FORJA_CLIENT_KEY, as it describes the whole API
+	if key := os.Getenv("FORJA_CLIENT_KEY"); key != "" {
+		fj.ServeClient(e, requireKey(key))
+	}
 
 	e.Logger.Fatal(e.Start(":8080"))
 }
@@ -0,0 +1,214 @@
+package forja
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Codecs describe where the values that the generated client has to convert
+// are, so that `time.Time` strings can be revived into Date objects for
+// example. They are generated as plain javascript objects:
+//
+//	"date"                                  a time.Time
+//	"duration"                              a time.Duration
+//	"bigint"                                a 64-bit integer, see Int64AsBigInt
+//	{ ref: "main.User" }                    a named type, see codecDefs
+//	{ object: { created: "date" } }         a struct, only with the fields to convert
+//	{ array: "date" }                       a slice or array
+//	{ record: "date" }                      a map
+//	{ union: "kind", variants: { ... } }    a Union, by discriminator value
+//
+// Types with nothing to convert have no codec at all.
+
+// codecRuntime is formatted with the export keyword of the Codec type and
+// transform, which are exported only by the runtime.ts of WriteTsClientDir.
+const codecRuntime = `
+%[1]stype Codec =
+  | 'date'
+  | 'duration'
+  | 'bigint'
+  | { ref: string }
+  | { object: Record<string, Codec> }
+  | { array: Codec }
+  | { record: Codec }
+  | { union: string; variants: Record<string, Codec> }
+
+%[1]sfunction transform(value: any, codec: Codec, decode: boolean): any {
+  if (value === null || value === undefined) {
+    return value
+  }
+  if (codec === 'date') {
+    if (decode) {
+      return new Date(value)
+    }
+    return value instanceof Date ? value.toISOString() : value
+  }
+  if (codec === 'duration') {
+    // Go sends durations as nanoseconds, the client uses milliseconds
+    return decode ? value / 1e6 : Math.round(value * 1e6)
+  }
+  if (codec === 'bigint') {
+    return decode ? BigInt(value) : value.toString()
+  }
+  if ('ref' in codec) {
+    return transform(value, codecs[codec.ref], decode)
+  }
+  if ('array' in codec) {
+    return (value as unknown[]).map((item) => transform(item, codec.array, decode))
+  }
+  if ('record' in codec) {
+    const result: Record<string, unknown> = {}
+    for (const key in value) {
+      result[key] = transform(value[key], codec.record, decode)
+    }
+    return result
+  }
+  if ('object' in codec) {
+    const result = { ...value }
+    for (const key in codec.object) {
+      if (key in result) {
+        result[key] = transform(result[key], codec.object[key], decode)
+      }
+    }
+    return result
+  }
+  const variant = codec.variants[value[codec.union]]
+  return variant ? transform(value, variant, decode) : value
+}
+`
+
+func isTime(t reflect.Type) bool {
+	return t.PkgPath() == "time" && t.Name() == "Time"
+}
+
+func isDuration(t reflect.Type) bool {
+	return t.PkgPath() == "time" && t.Name() == "Duration"
+}
+
+// needsCodec tells whether values of t contain anything that the generated
+// client has to convert.
+func (tp *typegen) needsCodec(t reflect.Type) bool {
+	return typeWalk[Int64Encoding]{
+		unions: tp.union,
+		field: func(field reflect.StructField) (Int64Encoding, fieldMatch) {
+			if !hasGeneratedType(field) {
+				return 0, fieldSkip
+			}
+			return fieldInt64Encoding(field, tp.defaultInt64Encoding), fieldWalk
+		},
+		match: func(t reflect.Type, encoding Int64Encoding) bool {
+			return tp.timeAsDate && isTime(t) ||
+				tp.durationAsMilliseconds && isDuration(t) ||
+				encoding == Int64AsBigInt && is64BitInt(t)
+		},
+	}.find(t, tp.int64Encoding)
+}
+
+// codec returns the codec of t, or "" if values of t do not need to be
+// converted. Named structs are registered in codecDefs and referenced by
+// name, as they can be recursive.
+func (tp *typegen) codec(t reflect.Type) string {
+	if !tp.needsCodec(t) {
+		return ""
+	}
+	tp.codecsUsed = true
+
+	if isTime(t) {
+		return `"date"`
+	}
+	if isDuration(t) {
+		return `"duration"`
+	}
+	if is64BitInt(t) {
+		return `"bigint"`
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return tp.codec(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return fmt.Sprintf("{ array: %s }", tp.codec(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("{ record: %s }", tp.codec(t.Elem()))
+	case reflect.Interface:
+		u := tp.unions[t]
+		var variants []string
+		for _, impl := range u.impls {
+			if codec := tp.codec(impl); codec != "" {
+				variants = append(variants, fmt.Sprintf("%q: %s", u.kindOf(impl), codec))
+			}
+		}
+		return fmt.Sprintf("{ union: %q, variants: { %s } }", u.tag, strings.Join(variants, ", "))
+	case reflect.Struct:
+		if isOption(t) || isPatch(t) || isUnion(t) {
+			value, _ := t.FieldByName("Value")
+			return tp.codec(value.Type)
+		}
+
+		if t.Name() == "" {
+			return tp.objectCodec(t)
+		}
+
+		name := qualifiedTypeName(t)
+		if _, exists := tp.codecDefs[name]; !exists {
+			// Register the name first, so that recursive types end up
+			// referencing themselves
+			tp.codecDefs[name] = ""
+			tp.codecDefs[name] = tp.objectCodec(t)
+		}
+		return fmt.Sprintf("{ ref: %q }", name)
+	default:
+		return ""
+	}
+}
+
+// hasGeneratedType tells whether the type of field in the generated client is
+// derived from its Go type, which is not the case of fields skipped or typed
+// by their ts tag, nor of quoted ones. Values of the others are left as is.
+func hasGeneratedType(field reflect.StructField) bool {
+	tag := parseTsTag(field)
+	return jsonFieldName(field) != "" && !tag.exclude && tag.typ == "" && !isQuoted(field)
+}
+
+func (tp *typegen) objectCodec(t reflect.Type) string {
+	outerEncoding := tp.int64Encoding
+	defer func() { tp.int64Encoding = outerEncoding }()
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !hasGeneratedType(field) {
+			continue
+		}
+		tp.int64Encoding = fieldInt64Encoding(field, tp.defaultInt64Encoding)
+		codec := tp.codec(field.Type)
+		if codec == "" {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%q: %s", jsonFieldName(field), codec))
+	}
+	return fmt.Sprintf("{ object: { %s } }", strings.Join(fields, ", "))
+}
+
+// printCodecs writes the codecs of all named types along with the runtime
+// that applies them, exported if export is set.
+func (tp *typegen) printCodecs(sb *strings.Builder, export bool) {
+	names := make([]string, 0, len(tp.codecDefs))
+	for name := range tp.codecDefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keyword := ""
+	if export {
+		keyword = "export "
+	}
+	fmt.Fprintf(sb, codecRuntime, keyword)
+	sb.WriteString("\nconst codecs: Record<string, Codec> = {\n")
+	for _, name := range names {
+		fmt.Fprintf(sb, "  %q: %s,\n", name, tp.codecDefs[name])
+	}
+	sb.WriteString("}\n")
+}
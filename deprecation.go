@@ -0,0 +1,118 @@
+package forja
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// deprecation holds the deprecation of a handler, see Deprecated.
+type deprecation struct {
+	message string
+	// since is the date the handler is deprecated from, sent in the
+	// Deprecation header
+	since  time.Time
+	sunset time.Time
+	calls  atomic.Int64
+}
+
+// Deprecated marks a handler as deprecated since the given date, which is
+// noted as @deprecated in the generated client, and sent in the Deprecation
+// and Sunset headers of its responses, as defined by RFC 9745 and RFC 8594.
+// sunset is the date after which the handler is removed, or zero if unknown.
+// Calls after that date are rejected if Config.RejectAfterSunset is set.
+//
+//	forja.AddHandlerWith(fj, createUser, forja.Deprecated(since, "use users.Create2", sunset))
+//
+// Both dates are fixed by the caller, so that every replica and restart of
+// the server sends the same ones.
+func Deprecated(since time.Time, message string, sunset time.Time) HandlerOption {
+	if since.IsZero() {
+		panic("Deprecated: the date the handler is deprecated since is required")
+	}
+	if !sunset.IsZero() && sunset.Before(since) {
+		panic(fmt.Sprintf("Deprecated: sunset %s is before the deprecation date %s",
+			sunset.Format(time.DateOnly), since.Format(time.DateOnly)))
+	}
+	return func(o *handlerOptions) {
+		o.deprecation = &deprecation{message: message, since: since, sunset: sunset}
+	}
+}
+
+// handle sets the deprecation headers of a response, and returns an error if
+// the handler is past its sunset date and calls must be rejected.
+func (d *deprecation) handle(c echo.Context, path string, rejectAfterSunset bool) error {
+	d.calls.Add(1)
+
+	header := c.Response().Header()
+	header.Set("Deprecation", fmt.Sprintf("@%d", d.since.Unix()))
+	if d.sunset.IsZero() {
+		return nil
+	}
+	header.Set("Sunset", d.sunset.UTC().Format(http.TimeFormat))
+	if rejectAfterSunset && time.Now().After(d.sunset) {
+		return echo.NewHTTPError(http.StatusGone,
+			fmt.Sprintf("%s was removed on %s", path, d.sunset.Format(time.DateOnly)))
+	}
+	return nil
+}
+
+// doc returns the doc comment of the handler with its deprecation appended.
+func (d *deprecation) doc(doc string) string {
+	note := d.message
+	if !d.sunset.IsZero() {
+		note = strings.TrimSpace(fmt.Sprintf("%s (sunset on %s)", note, d.sunset.Format(time.DateOnly)))
+	}
+	return withDeprecation(doc, note)
+}
+
+// withDeprecation appends a "Deprecated: " paragraph to a doc comment, unless
+// it has one already.
+func withDeprecation(doc, note string) string {
+	if strings.HasPrefix(doc, "Deprecated:") || strings.Contains(doc, "\n\nDeprecated:") {
+		return doc
+	}
+	paragraph := strings.TrimSpace("Deprecated: " + note)
+	if doc == "" {
+		return paragraph
+	}
+	return doc + "\n\n" + paragraph
+}
+
+// fieldDeprecation returns the deprecation note of a field, which is set with
+// the forja tag:
+//
+//	Name string `json:"name" forja:"deprecated=use fullName"`
+//	Age  int    `json:"age" forja:"deprecated"`
+func fieldDeprecation(field reflect.StructField) (string, bool) {
+	for _, opt := range strings.Split(field.Tag.Get("forja"), ",") {
+		if opt == "deprecated" {
+			return "", true
+		}
+		if note, ok := strings.CutPrefix(opt, "deprecated="); ok {
+			return note, true
+		}
+	}
+	return "", false
+}
+
+// DeprecatedCalls returns the number of calls received by every handler
+// marked as Deprecated, keyed by path, so that it can be checked whether
+// older clients still use them before they are removed.
+func (fj *Forja) DeprecatedCalls() map[string]int64 {
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+
+	calls := make(map[string]int64)
+	for pair := fj.handlers.Oldest(); pair != nil; pair = pair.Next() {
+		if d := pair.Value.deprecation; d != nil {
+			calls[pair.Key] = d.calls.Load()
+		}
+	}
+	return calls
+}
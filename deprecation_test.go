@@ -0,0 +1,35 @@
+package forja
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestDeprecatedHeaders(t *testing.T) {
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	e := echo.New()
+	fj := NewForja(e)
+	AddHandlerWith(fj, watchCount, Deprecated(since, "use watchGet", sunset))
+
+	req := httptest.NewRequest(http.MethodPost, "/forja.watchCount", strings.NewReader(`{"id":"1"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if got, want := rec.Header().Get("Deprecation"), "@1772323200"; got != want {
+		t.Errorf("got Deprecation %q, want %q", got, want)
+	}
+	if got, want := rec.Header().Get("Sunset"), "Fri, 01 Jan 2027 00:00:00 GMT"; got != want {
+		t.Errorf("got Sunset %q, want %q", got, want)
+	}
+	if got := fj.DeprecatedCalls()["/forja.watchCount"]; got != 1 {
+		t.Errorf("got %d deprecated calls, want 1", got)
+	}
+}
@@ -0,0 +1,198 @@
+package forja
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// docIndex holds the doc comments of the Go packages used by the generated
+// client, which are parsed from their sources the first time they are needed.
+type docIndex struct {
+	// dirs holds the source directory of the packages of handlers, which is
+	// needed for packages that cannot be found by their path, such as main.
+	dirs   map[string]string
+	loaded map[string]bool
+	// comments is keyed by pkgPath.Name for types and functions,
+	// pkgPath.Type.Field for fields and pkgPath.Type.Method for methods.
+	comments map[string]string
+}
+
+func newDocIndex() *docIndex {
+	return &docIndex{
+		dirs:     make(map[string]string),
+		loaded:   make(map[string]bool),
+		comments: make(map[string]string),
+	}
+}
+
+// addSource records the file declaring a function of the given package.
+func (d *docIndex) addSource(pkgPath, file string) {
+	if _, exists := d.dirs[pkgPath]; exists || !filepath.IsAbs(file) {
+		return
+	}
+	d.dirs[pkgPath] = filepath.Dir(file)
+}
+
+// lookup returns the doc comment of a declaration of the given package, or ""
+// if it is not documented or its sources cannot be found.
+func (d *docIndex) lookup(pkgPath string, names ...string) string {
+	if d == nil || pkgPath == "" {
+		return ""
+	}
+	if !d.loaded[pkgPath] {
+		d.loaded[pkgPath] = true
+		d.load(pkgPath)
+	}
+	return d.comments[pkgPath+"."+strings.Join(names, ".")]
+}
+
+func (d *docIndex) load(pkgPath string) {
+	dir, ok := d.dirs[pkgPath]
+	if !ok {
+		pkg, err := build.Import(pkgPath, ".", build.FindOnly)
+		if err != nil {
+			return
+		}
+		dir = pkg.Dir
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		d.addFile(pkgPath, file)
+	}
+}
+
+func (d *docIndex) addFile(pkgPath string, file *ast.File) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				name = receiverName(decl.Recv.List[0].Type) + "." + name
+			}
+			d.set(pkgPath+"."+name, decl.Doc)
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				doc := spec.Doc
+				// The doc of `type X struct{}` belongs to the declaration
+				if doc == nil && len(decl.Specs) == 1 {
+					doc = decl.Doc
+				}
+				typeName := pkgPath + "." + spec.Name.Name
+				d.set(typeName, doc)
+
+				if structType, ok := spec.Type.(*ast.StructType); ok {
+					for _, field := range structType.Fields.List {
+						doc := field.Doc
+						if doc == nil {
+							doc = field.Comment
+						}
+						for _, name := range field.Names {
+							d.set(typeName+"."+name.Name, doc)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (d *docIndex) set(key string, doc *ast.CommentGroup) {
+	if doc == nil {
+		return
+	}
+	if text := strings.TrimSpace(doc.Text()); text != "" {
+		d.comments[key] = text
+	}
+}
+
+// receiverName returns the name of the type of a method receiver, such as
+// Server for (s *Server) or Page for (p Page[T]).
+func receiverName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(expr.X)
+	case *ast.IndexExpr:
+		return receiverName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// funcDocName returns the name of a function as declared in its package, such
+// as Server.theHandler for github.com/acme/api.(*Server).theHandler
+func funcDocName(fullName, pkgPath string) string {
+	name := cleanHandlerName(strings.TrimPrefix(fullName, pkgPath+"."))
+	// Instances of generic functions are named Handler[...]
+	if bracket := strings.Index(name, "["); bracket != -1 {
+		name = name[:bracket]
+	}
+	return name
+}
+
+// jsDoc formats a Go doc comment as a JSDoc comment, converting the
+// "Deprecated:" paragraph into a @deprecated tag.
+func jsDoc(text, indent string) string {
+	if text == "" {
+		return ""
+	}
+
+	paragraphs := strings.Split(text, "\n\n")
+	for i, paragraph := range paragraphs {
+		if rest, ok := strings.CutPrefix(paragraph, "Deprecated:"); ok {
+			paragraphs[i] = strings.TrimSpace("@deprecated " + strings.TrimSpace(rest))
+		}
+	}
+	text = strings.ReplaceAll(strings.Join(paragraphs, "\n\n"), "*/", "*\\/")
+
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		return indent + "/** " + lines[0] + " */\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		if line == "" {
+			sb.WriteString(indent + " *\n")
+		} else {
+			sb.WriteString(indent + " * " + line + "\n")
+		}
+	}
+	sb.WriteString(indent + " */\n")
+	return sb.String()
+}
+
+// typeDoc returns the JSDoc of the named type t, if docs are enabled.
+func (tp *typegen) typeDoc(t reflect.Type) string {
+	if t.Name() == "" {
+		return ""
+	}
+	name, _ := splitGenericName(t.Name())
+	return jsDoc(tp.docs.lookup(t.PkgPath(), name), "")
+}
@@ -3,11 +3,12 @@ package forja
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"os/exec"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/labstack/echo/v4"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -23,13 +24,40 @@ type Router interface {
 // To add handlers to it use
 type Forja struct {
 	router         Router
-	handlers       *orderedmap.OrderedMap[string, reflect.Type] // "package.handler" -> Handler type
+	handlers       *orderedmap.OrderedMap[string, *handlerEntry] // "/namespace.handler" -> handler
+	namespaces     map[string]string                             // namespace -> package path
 	customTypes    []reflect.Type
 	typegen        *typegen
 	variables      *orderedmap.OrderedMap[string, any] // Custom variables to export in the TypeScript client
 	constVariables *orderedmap.OrderedMap[string, any] // Custom const variables to export with "as const"
 
 	config Config
+
+	// mu guards the registrations and the generations walking them, which
+	// may run concurrently once handlers are registered after startup while
+	// the client is served or watched.
+	mu sync.Mutex
+
+	// cachedSchemaHash caches SchemaHash, nil until it is computed
+	cachedSchemaHash atomic.Pointer[string]
+
+	// watchers are the clients kept up to date by WatchTsClient
+	watchers []*clientWatcher
+}
+
+type handlerEntry struct {
+	namespace   string
+	name        string
+	handlerType reflect.Type
+	// pkgPath and docName locate the declaration of the handler, for its doc
+	// comment, see docIndex.
+	pkgPath string
+	docName string
+	// deprecation is nil unless the handler is Deprecated
+	deprecation *deprecation
+	// rewrites caches the json rewrites of the params and result, nil until
+	// they are computed by the first request, see Forja.rewrites.
+	rewrites atomic.Pointer[handlerRewrites]
 }
 
 func NewForja(router Router) *Forja {
@@ -39,14 +67,72 @@ func NewForja(router Router) *Forja {
 type Config struct {
 	// OnErr, if not nil will be called if the handler responds with an error
 	OnErr func(c echo.Context, err error) error
+
+	// BrandedTypes, if true, emits named string and integer types such as
+	// `type UserID string` as branded typescript types, so that an OrgID cannot
+	// be passed where a UserID is expected. A constructor helper with the same
+	// name is generated for every branded type.
+	BrandedTypes bool
+
+	// TimeAsDate, if true, types time.Time values as Date in the generated
+	// client, which converts them from and to json strings.
+	TimeAsDate bool
+
+	// DurationAsMilliseconds, if true, types time.Duration values as
+	// milliseconds in the generated client, which converts them from and to
+	// the nanoseconds sent by the server.
+	DurationAsMilliseconds bool
+
+	// Int64Encoding tells how int64 and uint64 values are encoded, both by
+	// the server and in the generated client. It can be overridden per field
+	// with the `forja:"int64=number|string|bigint"` tag.
+	Int64Encoding Int64Encoding
+
+	// NonNullCollections, if true, sends nil slices and maps in responses as
+	// empty ones, so that they are typed as `T[]` and `Record<K, V>` instead of
+	// `T[] | null`. It can be overridden per field with the `forja:"nonnull"`
+	// and `forja:"nullable"` tags.
+	NonNullCollections bool
+
+	// TypeNaming names the packages of the generated types, and
+	// NamespaceNaming the namespaces of handlers in the generated client. Both
+	// default to LastSegmentNaming. Registering types or handlers from two
+	// packages with the same name panics. Use NestedNaming for namespaces
+	// mirroring the package hierarchy.
+	TypeNaming      NamingStrategy
+	NamespaceNaming NamingStrategy
+
+	// Docs, if true, emits the Go doc comments of handlers, types and fields
+	// as JSDoc in the generated client, including "Deprecated: " notes as
+	// @deprecated tags. The comments are read from the Go sources when the
+	// client is generated, so they must be available then.
+	Docs bool
+
+	// RejectAfterSunset, if true, responds with 410 Gone to calls to handlers
+	// past the sunset date they were Deprecated with.
+	RejectAfterSunset bool
+
+	// RejectStaleClients, if true, responds with 409 Conflict and the
+	// SCHEMA_MISMATCH message to clients generated from a different schema
+	// than the one of the server, instead of only flagging their responses
+	// with the X-Forja-Schema-Stale header. See Forja.SchemaHash.
+	RejectStaleClients bool
+
+	// Format tells how the generated client is formatted.
+	Format FormatOptions
 }
 
 func NewForjaWithConfig(router Router, config Config) *Forja {
+	if config.NamespaceNaming == nil {
+		config.NamespaceNaming = LastSegmentNaming
+	}
+
 	th := &Forja{
 		router:         router,
 		config:         config,
-		handlers:       orderedmap.New[string, reflect.Type](),
-		typegen:        newTypegen(),
+		handlers:       orderedmap.New[string, *handlerEntry](),
+		namespaces:     make(map[string]string),
+		typegen:        newTypegen(config),
 		variables:      orderedmap.New[string, any](),
 		constVariables: orderedmap.New[string, any](),
 	}
@@ -61,7 +147,53 @@ func cleanHandlerName(handlerName string) string {
 	return handlerName
 }
 
+// HandlerOption customizes how AddHandlerWith registers a handler.
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	name        string
+	namespace   string
+	deprecation *deprecation
+}
+
+// Name sets the name of the handler instead of deriving it from the name of
+// its function, which is needed for closures, generic functions and methods of
+// different structs with the same name. Dots nest the handler inside the
+// namespace, Name("users.create") is called as client.main.users.create.
+func Name(name string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.name = name
+	}
+}
+
+// Namespace sets the namespace of the handler instead of deriving it from its
+// package.
+func Namespace(namespace string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.namespace = namespace
+	}
+}
+
 func AddHandler[P any, R any](th *Forja, handler Handler[P, R]) {
+	addHandler(th, handler, "AddHandler")
+}
+
+// AddHandlerWith is like AddHandler, but the path of the handler can be set
+// explicitly so that it stays the same across refactors.
+//
+//	forja.AddHandlerWith(fj, h, forja.Name("users.create"), forja.Namespace("admin"))
+//
+// serves h on /admin.users.create.
+func AddHandlerWith[P any, R any](th *Forja, handler Handler[P, R], opts ...HandlerOption) {
+	addHandler(th, handler, "AddHandlerWith", opts...)
+}
+
+func addHandler[P any, R any](th *Forja, handler Handler[P, R], caller string, opts ...HandlerOption) {
+	var options handlerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	handlerFunc := runtime.FuncForPC(reflect.ValueOf(handler).Pointer())
 	fullName := handlerFunc.Name()
 
@@ -69,33 +201,113 @@ func AddHandler[P any, R any](th *Forja, handler Handler[P, R]) {
 	// https://github.com/golang/go/issues/52809#issuecomment-1122696583
 	fullName = strings.TrimSuffix(fullName, "-fm")
 
-	parts := strings.Split(fullName, "/")
-	parts = strings.Split(parts[len(parts)-1], ".")
+	file, _ := handlerFunc.FileLine(handlerFunc.Entry())
+	th.register(caller, fullName, file, options, reflect.TypeOf(handler), func(c echo.Context, params any) (any, error) {
+		return handler(c, *params.(*P))
+	})
+}
+
+// register serves a handler of the given type, named after the function
+// fullName declared in file unless set in options. invoke calls the handler
+// with a pointer to the bound params.
+func (th *Forja) register(caller, fullName, file string, options handlerOptions, handlerType reflect.Type,
+	invoke func(c echo.Context, params any) (any, error)) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	pkgPath := funcPackagePath(fullName)
+
+	packageName := options.namespace
+	if packageName == "" {
+		packageName = th.config.NamespaceNaming(pkgPath)
+		if other, exists := th.namespaces[packageName]; exists && other != pkgPath {
+			panic(fmt.Sprintf("%s: packages %s and %s are both named %s in the generated client, "+
+				"use a different Config.NamespaceNaming", caller, other, pkgPath, packageName))
+		}
+		th.namespaces[packageName] = pkgPath
+	} else if !isIdentifierPath(packageName) {
+		panic(fmt.Sprintf("%s: invalid namespace %q", caller, packageName))
+	}
 
-	packageName := parts[0]
-	handlerName := strings.Join(parts[1:], "_")
+	handlerName := options.name
+	if handlerName == "" {
+		handlerName = strings.ReplaceAll(strings.TrimPrefix(fullName, pkgPath+"."), ".", "_")
 
-	// if handler is a method of a struct pointer, we need to clean it, as it
-	// will come in the form of:
-	// (*Mystruct)_methodName
-	handlerName = cleanHandlerName(handlerName)
+		// if handler is a method of a struct pointer, we need to clean it, as it
+		// will come in the form of:
+		// (*Mystruct)_methodName
+		handlerName = cleanHandlerName(handlerName)
 
-	// Extract just the method name if it's a struct method (contains underscore)
-	if strings.Contains(handlerName, "_") {
-		handlerName = strings.Split(handlerName, "_")[1]
+		// Extract just the method name if it's a struct method (contains underscore)
+		if strings.Contains(handlerName, "_") {
+			handlerName = strings.Split(handlerName, "_")[1]
+		}
+	} else {
+		if !isIdentifierPath(handlerName) {
+			panic(fmt.Sprintf("%s: invalid handler name %q", caller, handlerName))
+		}
+		// users.create -> handler create in namespace main.users
+		if dot := strings.LastIndex(handlerName, "."); dot != -1 {
+			packageName += "." + handlerName[:dot]
+			handlerName = handlerName[dot+1:]
+		}
 	}
 
 	path := fmt.Sprintf("/%s.%s", packageName, handlerName)
+	if _, exists := th.handlers.Get(path); exists {
+		panic(fmt.Sprintf("%s: a handler is already registered on %s, use forja.Name to register %s under a different name",
+			caller, path, fullName))
+	}
+	if err := th.checkNamespaceConflict(packageName, handlerName); err != nil {
+		panic(fmt.Sprintf("%s: %s", caller, err))
+	}
 
-	th.handlers.Set(path, reflect.TypeOf(handler))
+	paramsType, resultType := handlerType.In(1), handlerType.Out(0)
+	for _, t := range []reflect.Type{paramsType, resultType} {
+		if err := th.typegen.checkTypeNames(t, make(map[reflect.Type]bool)); err != nil {
+			panic(fmt.Sprintf("%s: %s", caller, err))
+		}
+	}
+
+	entry := &handlerEntry{
+		namespace:   packageName,
+		name:        handlerName,
+		handlerType: handlerType,
+		pkgPath:     pkgPath,
+		docName:     funcDocName(fullName, pkgPath),
+		deprecation: options.deprecation,
+	}
+	th.handlers.Set(path, entry)
+	if th.typegen.docs != nil {
+		th.typegen.docs.addSource(pkgPath, file)
+	}
+	th.changed()
 
 	th.router.POST(path, func(c echo.Context) error {
-		var params P
-		if err := c.Bind(&params); err != nil {
+		if err := th.checkSchema(c); err != nil {
+			return err
+		}
+
+		if options.deprecation != nil {
+			if err := options.deprecation.handle(c, path, th.config.RejectAfterSunset); err != nil {
+				return err
+			}
+		}
+
+		rewrites := th.rewrites(entry)
+		if err := rewrites.params.rewriteParams(c, paramsType); err != nil {
+			return echo.NewHTTPError(400, err.Error())
+		}
+
+		params := reflect.New(paramsType)
+		if err := c.Bind(params.Interface()); err != nil {
+			return echo.NewHTTPError(400, err.Error())
+		}
+		if err := th.typegen.validateEnums(params.Elem()); err != nil {
 			return echo.NewHTTPError(400, err.Error())
 		}
 
-		result, err := handler(c, params)
+		result, err := invoke(c, params.Interface())
 		if err != nil {
 			if th.config.OnErr != nil {
 				err = th.config.OnErr(c, err)
@@ -106,19 +318,34 @@ func AddHandler[P any, R any](th *Forja, handler Handler[P, R]) {
 			})
 		}
 
+		result = rewrites.result.fillResult(result)
+		if rewrites.result.int64Strings {
+			data, err := rewrites.result.encodeResult(result, resultType)
+			if err != nil {
+				return err
+			}
+			return c.JSONBlob(200, data)
+		}
+
 		return c.JSON(200, result)
 	})
 }
 
+// WriteTsClient writes the generated client to path. The file is replaced
+// atomically, and only if its content changed, so that the frontend is not
+// reloaded when the program restarts without changes to the API.
 func (fj *Forja) WriteTsClient(path string) error {
 	generated := fj.GenerateTypescriptClient()
-	if err := os.WriteFile(path, []byte(generated), 0644); err != nil {
+	if _, err := writeFileIfChanged(path, []byte(generated)); err != nil {
 		return fmt.Errorf("failed to write TypeScript client to %s: %w", path, err)
 	}
 
 	return nil
 }
 
+// WriteTsClientWithCommand writes the client like WriteTsClient, then runs
+// cmd. The client is already formatted as set in Config.Format, so cmd is not
+// needed to run prettier on it.
 func (fj *Forja) WriteTsClientWithCommand(path string, cmd *exec.Cmd) error {
 	if err := fj.WriteTsClient(path); err != nil {
 		return err
@@ -132,6 +359,14 @@ func (fj *Forja) WriteTsClientWithCommand(path string, cmd *exec.Cmd) error {
 }
 
 func (fj *Forja) GenerateTypescriptClient() string {
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+	return fj.generateTypescriptClient()
+}
+
+// generateTypescriptClient generates the client, with fj.mu held.
+func (fj *Forja) generateTypescriptClient() string {
+	fj.typegen.reset()
 	output := new(strings.Builder)
 
 	// Generate ApiError type and ApiResponse type
@@ -139,7 +374,65 @@ func (fj *Forja) GenerateTypescriptClient() string {
 // AUTOGENERATED, DO NOT EDIT
 
 `)
+	fj.printVariables(output)
+	output.WriteString(apiResponseTypes)
+	output.WriteString("\n")
+
+	handlers := fj.clientHandlers()
+	apiClientTsDefinitions := newNamespaceTree()
+	for _, handler := range handlers {
+		fmt.Fprintf(output, "type %s = %s\n", handler.typeName, handler.typeDecl)
+		apiClientTsDefinitions.add(handler.namespace, handler.name, handler.typeName, handler.doc)
+	}
+
+	fmt.Fprintln(output, "export type ApiClient = {")
+	apiClientTsDefinitions.write(output, "  ")
+	fmt.Fprintln(output, "}")
+
+	fj.typegen.printTypeDefs(output)
+
+	fmt.Fprintf(output, "\nexport const SCHEMA_HASH = '%s'\n", fj.schemaHash())
+
+	// Generate createApiClient function
+	output.WriteString(apiClientConfig)
+	output.WriteString(`
+export function createApiClient(
+  baseUrl: string,
+  config?: ApiClientConfig
+): ApiClient {
+`)
+	output.WriteString(doFetchFunction)
+	output.WriteString("  const client: ApiClient = {\n")
 
+	// Generate client methods
+	clientMethods := newNamespaceTree()
+	for _, handler := range handlers {
+		clientMethods.add(handler.namespace, handler.name, handler.call, "")
+	}
+	clientMethods.write(output, "    ")
+
+	output.WriteString(`  }
+  return client
+}
+`)
+
+	if fj.typegen.codecsUsed {
+		fj.typegen.printCodecs(output, false)
+	}
+	if fj.typegen.bytesUsed {
+		output.WriteString(base64Helpers)
+	}
+
+	for _, typ := range fj.customTypes {
+		output.WriteString(fj.typegen.generateTypeDefinition(typ))
+	}
+
+	return formatTypescript(output.String(), fj.config.Format)
+}
+
+// printVariables writes the variables added with AddVariable and
+// AddConstVariable.
+func (fj *Forja) printVariables(output *strings.Builder) {
 	// Export custom variables
 	if fj.variables != nil && fj.variables.Len() > 0 {
 		for pair := fj.variables.Oldest(); pair != nil; pair = pair.Next() {
@@ -167,123 +460,120 @@ func (fj *Forja) GenerateTypescriptClient() string {
 			fmt.Fprintf(output, "export const %s = %s as const\n\n", name, string(jsonBytes))
 		}
 	}
-
-	output.WriteString(`export interface ApiError {
-  message: string
-  statusCode?: number
 }
-export type ApiResponse<T> =
-  | { data: T; error: null }
-  | { data: null; error: ApiError }
 
-`)
+// clientHandler is a handler as generated in the client.
+type clientHandler struct {
+	namespace string
+	name      string
+	doc       string
+	// typeName is the name of the function type of the handler, typeDecl
+	// its definition and call the implementation of the client method.
+	typeName string
+	typeDecl string
+	call     string
+}
 
-	type PackageName = string
-	type HandlerName = string
-	type Handler struct {
-		isInputEmpty bool
-		handlerType  reflect.Type
+// clientHandlers generates the types of all handlers, grouped by namespace.
+func (fj *Forja) clientHandlers() []*clientHandler {
+	namespaces := orderedmap.New[string, []*handlerEntry]()
+	for pair := fj.handlers.Oldest(); pair != nil; pair = pair.Next() {
+		entries, _ := namespaces.Get(pair.Value.namespace)
+		namespaces.Set(pair.Value.namespace, append(entries, pair.Value))
 	}
 
-	// Handler is a pointer so that we can update isInputEmpty later.
-	type Packages orderedmap.OrderedMap[PackageName, orderedmap.OrderedMap[HandlerName, *Handler]]
+	var handlers []*clientHandler
+	for pair := namespaces.Oldest(); pair != nil; pair = pair.Next() {
+		namespace := pair.Key
+		// Anonymous params and results are generated in the package named
+		// after the namespace
+		pkg := strings.ReplaceAll(namespace, ".", "_")
+		for _, entry := range pair.Value {
+			inputType := entry.handlerType.In(1)
+			outputType := resultType(entry.handlerType)
+			inputTypeName := fj.typegen.InputType(inputType, pkg, camelcaseNames(namespace, entry.name, "Input"))
+			outputTypeName := fj.typegen.OutputType(outputType, pkg, camelcaseNames(namespace, entry.name, "Output"))
+
+			handler := &clientHandler{
+				namespace: namespace,
+				name:      entry.name,
+				doc:       fj.typegen.docs.lookup(entry.pkgPath, entry.docName),
+				typeName:  camelcaseNames(namespace, entry.name, "Handler"),
+			}
+			if entry.deprecation != nil {
+				handler.doc = entry.deprecation.doc(handler.doc)
+			}
 
-	packages := orderedmap.New[PackageName, *orderedmap.OrderedMap[HandlerName, *Handler]]()
-	for pair := fj.handlers.Oldest(); pair != nil; pair = pair.Next() {
-		fullPath, handlerType := pair.Key, pair.Value
-		parts := strings.Split(fullPath, ".")
-		if len(parts) != 2 {
-			continue
-		}
-		packageName, handlerName := parts[0], parts[1]
-		packageParts := strings.Split(packageName, "/")
-		simplifiedPackageName := packageParts[len(packageParts)-1]
-		packageMap, exists := packages.Get(simplifiedPackageName)
-		if !exists {
-			packageMap = orderedmap.New[HandlerName, *Handler]()
-			packages.Set(simplifiedPackageName, packageMap)
-		}
-		packageMap.Set(handlerName, &Handler{
-			isInputEmpty: false,
-			handlerType:  handlerType,
-		})
-	}
-
-	type HandlerTsType = string
-	apiClientTsDefinitions := orderedmap.New[PackageName, *orderedmap.OrderedMap[HandlerName, HandlerTsType]]()
-
-	for packagePair := packages.Oldest(); packagePair != nil; packagePair = packagePair.Next() {
-		packageName, handlers := packagePair.Key, packagePair.Value
-		for handlerPair := handlers.Oldest(); handlerPair != nil; handlerPair = handlerPair.Next() {
-			handlerName, handler := handlerPair.Key, handlerPair.Value
-			_, _, _ = packageName, handlerName, handler.handlerType
-			inputType := handler.handlerType.In(1)
-			outputType := handler.handlerType.Out(0).Elem()
-			inputTypeName := fj.typegen.FillTypeDefinitions(inputType)
-			outputTypeName := fj.typegen.FillTypeDefinitions(outputType)
-
-			handlerTsName := camelcaseNames(packageName, handlerName, "Handler")
-
-			isInputEmptyStruct := inputType.Kind() == reflect.Struct && inputType.NumField() == 0
-			if isInputEmptyStruct {
-				handlerMap, _ := packages.Get(packageName)
-				handlerItem, _ := handlerMap.Get(handlerName)
-				handlerItem.isInputEmpty = true
-				fmt.Fprintf(output,
-					"type %s = () => Promise<ApiResponse<%s>>\n",
-					handlerTsName, outputTypeName)
+			isInputEmpty := inputType.Kind() == reflect.Struct && inputType.NumField() == 0
+			args := []string{fmt.Sprintf("\"%s.%s\"", namespace, entry.name)}
+			if isInputEmpty {
+				handler.typeDecl = fmt.Sprintf("() => Promise<ApiResponse<%s>>", outputTypeName)
 			} else {
-				fmt.Fprintf(output,
-					"type %s = (params: %s) => Promise<ApiResponse<%s>>\n",
-					handlerTsName, inputTypeName, outputTypeName)
+				handler.typeDecl = fmt.Sprintf("(params: %s) => Promise<ApiResponse<%s>>", inputTypeName, outputTypeName)
+				if inputCodec := fj.typegen.codec(inputType); inputCodec != "" {
+					args = append(args, fmt.Sprintf("transform(params, %s, false)", inputCodec))
+				} else {
+					args = append(args, "params")
+				}
 			}
-
-			packageMap, exists := apiClientTsDefinitions.Get(packageName)
-			if !exists {
-				packageMap = orderedmap.New[HandlerName, HandlerTsType]()
-				apiClientTsDefinitions.Set(packageName, packageMap)
+			if outputCodec := fj.typegen.codec(outputType); outputCodec != "" {
+				if isInputEmpty {
+					args = append(args, "undefined")
+				}
+				args = append(args, fmt.Sprintf("(data) => transform(data, %s, true)", outputCodec))
 			}
 
-			packageMap.Set(handlerName, handlerTsName)
-		}
-	}
-
-	fmt.Fprintln(output, "export type ApiClient = {")
-	for pair := apiClientTsDefinitions.Oldest(); pair != nil; pair = pair.Next() {
-		packageName, packageTypeDef := pair.Key, pair.Value
-		fmt.Fprintln(output, "  ", packageName, ": {")
-		for handlerPair := packageTypeDef.Oldest(); handlerPair != nil; handlerPair = handlerPair.Next() {
-			handlerName, handlerTypeName := handlerPair.Key, handlerPair.Value
-			fmt.Fprintln(output, "    ", handlerName, ": ", handlerTypeName, ",")
+			if isInputEmpty {
+				handler.call = fmt.Sprintf("() => doFetch(%s)", strings.Join(args, ", "))
+			} else {
+				handler.call = fmt.Sprintf("(params) => doFetch(%s)", strings.Join(args, ", "))
+			}
+			handlers = append(handlers, handler)
 		}
-		fmt.Fprintln(output, "  ", "},")
 	}
-	fmt.Fprintln(output, "}")
+	return handlers
+}
 
-	fj.typegen.printTypeDefs(output)
+const apiResponseTypes = `export interface ApiError {
+  message: string
+  statusCode?: number
+}
+export type ApiResponse<T> =
+  | { data: T; error: null }
+  | { data: null; error: ApiError }
+`
 
-	// Generate createApiClient function
-	output.WriteString(`
+const apiClientConfig = `
 export type ApiClientConfig = {
   beforeRequest?: (config: RequestInit) => void | Promise<void>
+  /**
+   * Called when the server runs a different version of the API than the one
+   * this client was generated from, to prompt a reload for example
+   */
+  onSchemaMismatch?: () => void
 }
 
 export const REQUEST_ABORTED = 'REQUEST_ABORTED'
-
-export function createApiClient(
-  baseUrl: string,
-  config?: ApiClientConfig
-): ApiClient {
-  async function doFetch(path: string, params?: unknown) {
+export const SCHEMA_MISMATCH = 'SCHEMA_MISMATCH'
+`
+
+// doFetchFunction sends the requests of all client methods, with the baseUrl
+// and config given to createApiClient.
+const doFetchFunction = `  async function doFetch(
+    path: string,
+    params?: unknown,
+    decode?: (data: unknown) => unknown
+  ) {
     try {
-	  if (params === undefined) {
-	  	params = {}
-	  }
+      if (params === undefined) {
+        params = {}
+      }
 
       const requestConfig: RequestInit = {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
+          "X-Forja-Schema": SCHEMA_HASH,
         },
         body: JSON.stringify(params ?? {}),
       }
@@ -293,6 +583,9 @@ export function createApiClient(
       }
 
       const response = await fetch(` + "`${baseUrl}/${path}`" + `, requestConfig)
+      if (response.headers.get("X-Forja-Schema-Stale") !== null) {
+        config?.onSchemaMismatch?.()
+      }
       if (!response.ok) {
         const data = await response.json()
         const message = data.message
@@ -303,7 +596,7 @@ export function createApiClient(
         }
       }
       const data = await response.json()
-      return { data, error: null }
+      return { data: decode ? decode(data) : data, error: null }
     } catch (error) {
       if (error instanceof DOMException && error.name === 'AbortError') {
         return {
@@ -322,61 +615,71 @@ export function createApiClient(
       }
     }
   }
-  const client: ApiClient = {
-`)
+`
 
-	// Generate client methods
-	for packagePair := packages.Oldest(); packagePair != nil; packagePair = packagePair.Next() {
-		packageName, handlers := packagePair.Key, packagePair.Value
-		fmt.Fprintf(output, "    %s: {\n", packageName)
-		for handlerPair := handlers.Oldest(); handlerPair != nil; handlerPair = handlerPair.Next() {
-			handlerName, handler := handlerPair.Key, handlerPair.Value
-			var callback string
-			if handler.isInputEmpty {
-				callback = fmt.Sprintf(
-					"      %s: () => doFetch(\"%s.%s\"),\n",
-					handlerName, packageName, handlerName)
-			} else {
-				callback = fmt.Sprintf(
-					"      %s: (params) => doFetch(\"%s.%s\", params),\n",
-					handlerName, packageName, handlerName)
-			}
+func (fj *Forja) AddType(typ any) {
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
 
-			output.WriteString(callback)
-		}
-		output.WriteString("    },\n")
+	t := reflect.TypeOf(typ)
+	if err := fj.typegen.checkTypeNames(t, make(map[reflect.Type]bool)); err != nil {
+		panic(fmt.Sprintf("AddType: %s", err))
 	}
-
-	output.WriteString(`  }
-  return client
+	fj.customTypes = append(fj.customTypes, t)
+	fj.changed()
 }
-`)
 
-	for _, typ := range fj.customTypes {
-		output.WriteString(fj.typegen.generateTypeDefinition(typ))
+// AddEnum registers the allowed values of the named type T, which must be a
+// string, number or boolean type. T is generated as an union of its values,
+// and requests containing any other value of T are rejected. Types declaring
+// a `Values() []T` method do not need to be registered.
+//
+//	forja.AddEnum(fj, StatusActive, StatusArchived)
+func AddEnum[T comparable](fj *Forja, values ...T) {
+	t := reflect.TypeFor[T]()
+	if !isEnumKind(t) {
+		panic(fmt.Sprintf("AddEnum: %s is not a string, number or boolean type", t))
+	}
+	anyValues := make([]any, len(values))
+	for i, value := range values {
+		anyValues[i] = value
 	}
 
-	result := output.String()
-
-	return result
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+	fj.typegen.addEnum(t, anyValues)
+	fj.changed()
+	if err := fj.typegen.checkTypeNames(t, make(map[reflect.Type]bool)); err != nil {
+		panic(fmt.Sprintf("AddEnum: %s", err))
+	}
 }
 
-func (fj *Forja) AddType(typ any) {
-	fj.customTypes = append(fj.customTypes, reflect.TypeOf(typ))
+// resultType returns the type of the json sent by the given handler, which is
+// the same for *T and T results.
+func resultType(handlerType reflect.Type) reflect.Type {
+	t := handlerType.Out(0)
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
 }
 
 func camelcaseNames(names ...string) string {
-	for i, name := range names {
-		if len(name) > 0 {
-			names[i] = strings.ToUpper(name[:1]) + name[1:]
+	var sb strings.Builder
+	for _, name := range names {
+		// Namespaces set with forja.Name can contain dots, admin.users
+		for _, part := range strings.Split(name, ".") {
+			if len(part) > 0 {
+				sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+			}
 		}
 	}
-	return strings.Join(names, "")
+	return sb.String()
 }
 
 // Option is a special type that makes it easy to encode optional values and
-// enums (see examples). We do not support generics (yet), so this type is handled
-// as a separate entity when "compiling" types to typescript.
+// enums (see examples). Unlike other generic types, it is not generated as a
+// generic typescript type: Option[T] fields are generated as optional T fields.
 type Option[T any] struct {
 	IsValid bool
 	Value   T
@@ -409,20 +712,28 @@ func (o *Option[T]) UnmarshalJSON(data []byte) error {
 // AddVariable adds a custom variable to be exported in the TypeScript client.
 // The value will be JSON encoded and exported as a TypeScript variable.
 func (fj *Forja) AddVariable(variableName string, value any) {
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+
 	// Store the variable name and value
 	if fj.variables == nil {
 		fj.variables = orderedmap.New[string, any]()
 	}
 	fj.variables.Set(variableName, value)
+	fj.changed()
 }
 
 // AddConstVariable adds a custom variable to be exported in the TypeScript client
 // with the "as const" assertion, which preserves literal types in TypeScript.
 // This makes the types more precise than regular variables.
 func (fj *Forja) AddConstVariable(variableName string, value any) {
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+
 	// Store the variable name and value with a flag indicating it should use "as const"
 	if fj.constVariables == nil {
 		fj.constVariables = orderedmap.New[string, any]()
 	}
 	fj.constVariables.Set(variableName, value)
+	fj.changed()
 }
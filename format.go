@@ -0,0 +1,700 @@
+package forja
+
+import (
+	"strings"
+)
+
+// FormatOptions tells how the generated client is formatted. The zero value
+// formats it with two spaces, single quotes, no semicolons and trailing
+// commas, so that the generated file is stable without running prettier on
+// it. Long lines are not wrapped.
+type FormatOptions struct {
+	// Indent is the indentation of a nesting level, two spaces if empty. Use
+	// "\t" to indent with tabs.
+	Indent string
+
+	// DoubleQuotes, if true, quotes strings with " instead of '. Strings
+	// containing more of the preferred quotes than of the other ones keep the
+	// other ones, to avoid escapes.
+	DoubleQuotes bool
+
+	// Semicolons, if true, ends statements and type members with semicolons.
+	Semicolons bool
+
+	// NoTrailingCommas, if true, removes the commas after the last element
+	// of multiline objects, arrays and parameter lists.
+	NoTrailingCommas bool
+}
+
+type fmtTokenKind int
+
+const (
+	tokWord fmtTokenKind = iota
+	tokString
+	tokTemplate
+	tokRegex
+	tokComment
+	tokPunct
+	tokSpace
+	tokNewline
+)
+
+type fmtToken struct {
+	kind fmtTokenKind
+	text string
+}
+
+// punctuators are the multi character punctuators the formatter needs to
+// tell apart, longest first.
+var punctuators = []string{
+	"...", "===", "!==",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--", "+=", "-=", "*=", "/=",
+}
+
+// tokenize splits typescript source into tokens. It only knows enough of the
+// language to format the code forja generates: template and regular
+// expression literals are kept as a single token.
+func tokenize(src string) []fmtToken {
+	var tokens []fmtToken
+	for i := 0; i < len(src); {
+		ch := src[i]
+		start := i
+		var kind fmtTokenKind
+		switch {
+		case ch == '\n':
+			i++
+			kind = tokNewline
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\r') {
+				i++
+			}
+			kind = tokSpace
+		case strings.HasPrefix(src[i:], "//"):
+			i = indexFrom(src, i, "\n")
+			kind = tokComment
+		case strings.HasPrefix(src[i:], "/*"):
+			i = indexFrom(src, i+2, "*/") + 2
+			kind = tokComment
+		case ch == '\'' || ch == '"':
+			i = scanString(src, i)
+			kind = tokString
+		case ch == '`':
+			i = scanTemplate(src, i)
+			kind = tokTemplate
+		case ch == '/' && regexAllowed(tokens) && scanRegex(src, i) != -1:
+			i = scanRegex(src, i)
+			kind = tokRegex
+		case isWordByte(ch):
+			for i < len(src) && isWordByte(src[i]) {
+				i++
+			}
+			kind = tokWord
+		default:
+			i++
+			for _, p := range punctuators {
+				if strings.HasPrefix(src[start:], p) {
+					i = start + len(p)
+					break
+				}
+			}
+			kind = tokPunct
+		}
+		if i > len(src) {
+			i = len(src)
+		}
+		tokens = append(tokens, fmtToken{kind, src[start:i]})
+	}
+	return tokens
+}
+
+// regexKeywords are the keywords after which a slash starts a regular
+// expression instead of a division.
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "instanceof": true, "await": true, "yield": true,
+}
+
+// regexAllowed tells whether a slash after tokens starts a regular
+// expression, which is the case where a value is expected.
+func regexAllowed(tokens []fmtToken) bool {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tok := tokens[i]; tok.kind {
+		case tokSpace, tokNewline, tokComment:
+			continue
+		case tokPunct:
+			return tok.text != ")" && tok.text != "]" && tok.text != "}"
+		case tokWord:
+			return regexKeywords[tok.text]
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// scanRegex returns the end of the regular expression literal starting at i,
+// including its flags, or -1 if it does not end on the same line.
+func scanRegex(src string, i int) int {
+	inClass := false
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '\n':
+			return -1
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if inClass {
+				continue
+			}
+			for i++; i < len(src) && isWordByte(src[i]); i++ {
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// indexFrom returns the index of substr in s after from, or len(s).
+func indexFrom(s string, from int, substr string) int {
+	if i := strings.Index(s[from:], substr); i != -1 {
+		return from + i
+	}
+	return len(s)
+}
+
+func isWordByte(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' ||
+		ch == '_' || ch == '$' || ch >= 0x80
+}
+
+func isIdentifier(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isWordByte(s[i]) || s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// scanString returns the end of the string literal starting at i.
+func scanString(src string, i int) int {
+	quote := src[i]
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote, '\n':
+			return i + 1
+		}
+	}
+	return i
+}
+
+// scanTemplate returns the end of the template literal starting at i.
+func scanTemplate(src string, i int) int {
+	for i++; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case src[i] == '`':
+			return i + 1
+		case strings.HasPrefix(src[i:], "${"):
+			depth := 0
+			for i += 2; i < len(src); i++ {
+				if src[i] == '\'' || src[i] == '"' {
+					i = scanString(src, i) - 1
+				} else if src[i] == '`' {
+					i = scanTemplate(src, i) - 1
+				} else if src[i] == '{' {
+					depth++
+				} else if src[i] == '}' {
+					if depth == 0 {
+						break
+					}
+					depth--
+				}
+			}
+		}
+	}
+	return i
+}
+
+// requote returns the string literal s quoted with quote, unless it contains
+// more of those than of the other quotes.
+func requote(s string, quote byte) string {
+	if len(s) < 2 {
+		return s
+	}
+	original, content := s[0], s[1:len(s)-1]
+	other := byte('"')
+	if quote == '"' {
+		other = '\''
+	}
+	if strings.Count(content, string(quote)) > strings.Count(content, string(other)) {
+		quote = other
+	}
+	if quote == original {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.WriteByte(quote)
+	for i := 0; i < len(content); i++ {
+		switch ch := content[i]; {
+		case ch == '\\' && i+1 < len(content):
+			i++
+			if content[i] != original {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(content[i])
+		case ch == quote:
+			sb.WriteString("\\" + string(quote))
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	sb.WriteByte(quote)
+	return sb.String()
+}
+
+type fmtContextKind int
+
+const (
+	// ctxBlock holds statements
+	ctxBlock fmtContextKind = iota
+	// ctxObject holds the properties of an object literal
+	ctxObject
+	// ctxType holds the members of a type literal or interface
+	ctxType
+	// ctxList holds the elements of parentheses or brackets
+	ctxList
+)
+
+type fmtContext struct {
+	kind   fmtContextKind
+	open   string
+	indent int
+	// opener is the position of the opening token in the output
+	opener fmtPosition
+	// list is whether the context holds commas, so that parentheses can get
+	// a trailing comma without changing a grouped expression
+	list bool
+	// typeMode is whether braces in a list open type literals
+	typeMode bool
+	// noSemicolon is whether closing the context does not end a statement,
+	// as for function bodies and interfaces
+	noSemicolon bool
+
+	// stmtStart is whether the next token of a block starts a statement,
+	// and stmtKind the type or interface keyword of the current one
+	stmtStart bool
+	stmtKind  string
+}
+
+type fmtPosition struct {
+	line, index int
+}
+
+// continuationEnds are the tokens after which a line continues on the next
+// one, and continuationStarts the tokens continuing the previous line.
+var (
+	continuationEnds = map[string]bool{
+		"{": true, "(": true, "[": true, ",": true, ";": true, ":": true, "?": true, ".": true, "?.": true,
+		"=": true, "=>": true, "|": true, "&": true, "||": true, "&&": true, "??": true, "!": true,
+		"+": true, "-": true, "*": true, "/": true, "<": true, "==": true, "===": true, "!=": true,
+		"!==": true, "<=": true, ">=": true, "+=": true, "-=": true, "*=": true, "/=": true, "...": true,
+	}
+	continuationStarts = map[string]bool{
+		")": true, "]": true, ",": true, ":": true, "?": true, ".": true, "?.": true, "=": true,
+		"=>": true, "|": true, "&": true, "||": true, "&&": true, "??": true, "+": true, "*": true,
+		"/": true, "<": true, ">": true, "==": true, "===": true, "!=": true, "!==": true,
+		"<=": true, ">=": true, "{": true, "as": true, "in": true, "instanceof": true,
+		"extends": true, "else": true, "catch": true, "finally": true,
+	}
+	// openKeywords are the keywords that cannot end a statement
+	openKeywords = map[string]bool{
+		"else": true, "try": true, "do": true, "export": true, "declare": true, "async": true,
+		"function": true, "type": true, "interface": true, "new": true, "typeof": true, "in": true,
+		"of": true, "instanceof": true, "extends": true, "await": true,
+	}
+	// objectOpeners are the tokens after which braces open an object
+	// literal instead of a block
+	objectOpeners = map[string]bool{
+		"=": true, ":": true, "(": true, ",": true, "[": true, "?": true, "||": true, "&&": true,
+		"??": true, "|": true, "&": true, "...": true, "return": true,
+	}
+	// indentedContinuations are the tokens after which, or starting with
+	// which, a line continuing a statement is indented
+	indentedContinuations = map[string]bool{
+		"=": true, ":": true, "?": true, "=>": true, "|": true, "&": true, "||": true, "&&": true,
+		"??": true, "+": true, ".": true, "?.": true,
+	}
+	closers = map[string]string{"}": "{", ")": "(", "]": "["}
+)
+
+// formatter re-prints the tokens of generated typescript line by line. It
+// re-indents lines by nesting, normalizes quotes, adds or removes semicolons
+// and trailing commas, and collapses blank lines, but keeps the spacing
+// within lines, which forja already generates consistently.
+type formatter struct {
+	options FormatOptions
+	tokens  []fmtToken
+	lines   [][]fmtToken
+	indents []int
+	stack   []*fmtContext
+
+	// prev is the position of the last significant token, that is neither
+	// a space, a newline nor a comment, and prevOnLine whether it is on the
+	// current line
+	prev       fmtPosition
+	prevOnLine bool
+	// closedNoSemicolon is whether the last closed context was one whose
+	// closing does not end a statement
+	closedNoSemicolon bool
+}
+
+// formatTypescript formats the typescript source generated by forja.
+func formatTypescript(src string, options FormatOptions) string {
+	if options.Indent == "" {
+		options.Indent = "  "
+	}
+	f := &formatter{
+		options: options,
+		tokens:  tokenize(src),
+		stack:   []*fmtContext{{kind: ctxBlock, indent: -1, stmtStart: true}},
+		prev:    fmtPosition{-1, -1},
+	}
+	f.format()
+	return f.String()
+}
+
+func (f *formatter) top() *fmtContext {
+	return f.stack[len(f.stack)-1]
+}
+
+func (f *formatter) token(pos fmtPosition) *fmtToken {
+	if pos.line < 0 {
+		return nil
+	}
+	return &f.lines[pos.line][pos.index]
+}
+
+func (f *formatter) prevText() string {
+	if tok := f.token(f.prev); tok != nil {
+		return tok.text
+	}
+	return ""
+}
+
+// next returns the first significant token after i.
+func (f *formatter) next(i int) *fmtToken {
+	for i++; i < len(f.tokens); i++ {
+		if kind := f.tokens[i].kind; kind != tokSpace && kind != tokNewline && kind != tokComment {
+			return &f.tokens[i]
+		}
+	}
+	return nil
+}
+
+func (f *formatter) format() {
+	lineOpen, newlines := false, 0
+	for i := 0; i < len(f.tokens); i++ {
+		tok := f.tokens[i]
+		switch tok.kind {
+		case tokNewline:
+			if !lineOpen {
+				newlines++
+				continue
+			}
+			// Empty braces are kept on a single line
+			if f.prevOnLine && f.isOpener(f.prev) {
+				if closer := f.closerAfter(i); closer != -1 {
+					i = closer - 1
+					continue
+				}
+			}
+			f.endLine(i)
+			lineOpen, newlines = false, 1
+			f.prevOnLine = false
+		case tokSpace:
+			if lineOpen && i+1 < len(f.tokens) && f.tokens[i+1].kind != tokNewline {
+				f.lines[len(f.lines)-1] = append(f.lines[len(f.lines)-1], fmtToken{tokSpace, " "})
+			}
+		default:
+			if !lineOpen {
+				f.startLine(tok, newlines > 1)
+				lineOpen = true
+			}
+			f.add(i, tok)
+		}
+	}
+	if lineOpen {
+		f.endLine(len(f.tokens))
+	}
+}
+
+func (f *formatter) isOpener(pos fmtPosition) bool {
+	tok := f.token(pos)
+	return tok != nil && tok.kind == tokPunct && (tok.text == "{" || tok.text == "(" || tok.text == "[")
+}
+
+// closerAfter returns the index of the token closing the last significant
+// one if only spaces and newlines are between them after i, or -1.
+func (f *formatter) closerAfter(i int) int {
+	for i++; i < len(f.tokens); i++ {
+		switch f.tokens[i].kind {
+		case tokSpace, tokNewline:
+			continue
+		case tokPunct:
+			if closers[f.tokens[i].text] == f.prevText() {
+				return i
+			}
+		}
+		return -1
+	}
+	return -1
+}
+
+// startLine indents the line starting with tok, and fixes the trailing
+// comma of the previous one if tok closes a multiline list.
+func (f *formatter) startLine(tok fmtToken, blank bool) {
+	ctx := f.top()
+	isCloser := tok.kind == tokPunct && closers[tok.text] != "" && closers[tok.text] == ctx.open
+
+	// Blank lines are collapsed, and removed at the start of the file and
+	// around the content of blocks
+	if blank && !isCloser && f.prev.line >= 0 && !f.isOpener(f.prev) {
+		f.lines = append(f.lines, nil)
+		f.indents = append(f.indents, 0)
+	}
+	f.lines = append(f.lines, nil)
+	f.indents = append(f.indents, 0)
+	line := len(f.lines) - 1
+
+	if isCloser {
+		f.indents[line] = ctx.indent
+		f.fixTrailingComma(ctx)
+		return
+	}
+
+	indent := ctx.indent + 1
+	if tok.kind == tokPunct && indentedContinuations[tok.text] || f.prev.line >= 0 && indentedContinuations[f.prevText()] {
+		indent++
+	}
+	f.indents[line] = indent
+}
+
+func (f *formatter) fixTrailingComma(ctx *fmtContext) {
+	if f.prev == ctx.opener || f.prev.line < 0 {
+		return
+	}
+	wanted := ctx.kind == ctxObject || ctx.kind == ctxList && (ctx.open == "[" || ctx.list)
+	if !wanted {
+		return
+	}
+	hasComma := f.prevText() == ","
+	switch {
+	case hasComma && f.options.NoTrailingCommas:
+		f.remove(f.prev)
+	case !hasComma && !f.options.NoTrailingCommas:
+		f.insertAfterPrev(",")
+	}
+}
+
+func (f *formatter) remove(pos fmtPosition) {
+	line := f.lines[pos.line]
+	f.lines[pos.line] = append(line[:pos.index:pos.index], line[pos.index+1:]...)
+	f.prev = fmtPosition{-1, -1}
+	for l := pos.line; l >= 0 && f.prev.line < 0; l-- {
+		for i := len(f.lines[l]) - 1; i >= 0; i-- {
+			if l == pos.line && i >= pos.index {
+				continue
+			}
+			if kind := f.lines[l][i].kind; kind != tokSpace && kind != tokComment {
+				f.prev = fmtPosition{l, i}
+				break
+			}
+		}
+	}
+}
+
+func (f *formatter) insertAfterPrev(text string) {
+	pos := f.prev
+	line := f.lines[pos.line]
+	line = append(line[:pos.index+1:pos.index+1], append([]fmtToken{{tokPunct, text}}, line[pos.index+1:]...)...)
+	f.lines[pos.line] = line
+	f.prev = fmtPosition{pos.line, pos.index + 1}
+}
+
+// add appends the significant or comment token tok, the i-th one, to the
+// current line, and updates the nesting.
+func (f *formatter) add(i int, tok fmtToken) {
+	line := len(f.lines) - 1
+	switch tok.kind {
+	case tokComment:
+		if strings.Contains(tok.text, "\n") {
+			tok.text = f.reindentComment(tok.text, f.indents[line])
+		}
+		f.lines[line] = append(f.lines[line], tok)
+		return
+	case tokString:
+		quote := byte('\'')
+		if f.options.DoubleQuotes {
+			quote = '"'
+		}
+		tok.text = requote(tok.text, quote)
+
+		// Keys of objects, such as the ones of variables encoded in json,
+		// are only quoted when needed
+		ctx := f.top()
+		if next := f.next(i); ctx.kind == ctxObject && (f.prev == ctx.opener || f.prevText() == ",") &&
+			next != nil && next.text == ":" && isIdentifier(tok.text[1:len(tok.text)-1]) {
+			tok.kind, tok.text = tokWord, tok.text[1:len(tok.text)-1]
+		}
+	}
+
+	ctx := f.top()
+	prevText := f.prevText()
+	f.lines[line] = append(f.lines[line], tok)
+	pos := fmtPosition{line, len(f.lines[line]) - 1}
+
+	if ctx.kind == ctxBlock && ctx.stmtStart {
+		switch tok.text {
+		case "export", "declare", "default":
+		case "type", "interface":
+			ctx.stmtStart, ctx.stmtKind = false, tok.text
+		default:
+			ctx.stmtStart, ctx.stmtKind = false, ""
+		}
+	}
+
+	if tok.kind == tokPunct {
+		switch tok.text {
+		case "{", "(", "[":
+			inner := &fmtContext{
+				kind:     ctxList,
+				open:     tok.text,
+				indent:   f.indents[line],
+				opener:   pos,
+				typeMode: ctx.kind == ctxType || ctx.kind == ctxBlock && ctx.stmtKind != "" || ctx.kind == ctxList && ctx.typeMode,
+			}
+			if tok.text == "{" {
+				switch {
+				case inner.typeMode:
+					inner.kind = ctxType
+					inner.noSemicolon = ctx.kind == ctxBlock && ctx.stmtKind == "interface"
+				case prevText == "=>":
+					inner.kind, inner.stmtStart = ctxBlock, true
+				case objectOpeners[prevText]:
+					inner.kind = ctxObject
+				default:
+					inner.kind, inner.stmtStart, inner.noSemicolon = ctxBlock, true, true
+				}
+			}
+			f.stack = append(f.stack, inner)
+		case "}", ")", "]":
+			if len(f.stack) > 1 && closers[tok.text] == ctx.open {
+				f.stack = f.stack[:len(f.stack)-1]
+				f.closedNoSemicolon = ctx.noSemicolon
+				if parent := f.top(); ctx.noSemicolon && parent.kind == ctxBlock {
+					parent.stmtStart, parent.stmtKind = true, ""
+				}
+			}
+		case ",":
+			ctx.list = true
+		case ";":
+			if ctx.kind == ctxBlock {
+				ctx.stmtStart, ctx.stmtKind = true, ""
+			}
+		}
+	}
+
+	f.prev = pos
+	f.prevOnLine = true
+}
+
+// endLine adds or removes the semicolon ending the current line, before the
+// i-th token.
+func (f *formatter) endLine(i int) {
+	if !f.prevOnLine {
+		return
+	}
+	ctx := f.top()
+	if ctx.kind != ctxBlock && ctx.kind != ctxType {
+		return
+	}
+
+	last := f.token(f.prev)
+	next := f.next(i)
+	if ctx.kind == ctxType && last.text == "," {
+		// Members are separated like statements
+		f.remove(f.prev)
+		if f.options.Semicolons {
+			f.insertAfterPrev(";")
+		}
+		return
+	}
+	if last.text == ";" {
+		if ctx.kind == ctxBlock {
+			ctx.stmtStart, ctx.stmtKind = true, ""
+		}
+		if !f.options.Semicolons && (next == nil || next.text != "(" && next.text != "[" && next.kind != tokTemplate) {
+			f.remove(f.prev)
+		}
+		return
+	}
+
+	if last.kind == tokPunct && continuationEnds[last.text] || last.kind == tokWord && openKeywords[last.text] {
+		return
+	}
+	if last.text == "}" && f.closedNoSemicolon {
+		return
+	}
+	if next != nil && (next.kind == tokPunct || next.kind == tokWord) && continuationStarts[next.text] {
+		return
+	}
+
+	if ctx.kind == ctxBlock {
+		ctx.stmtStart, ctx.stmtKind = true, ""
+	}
+	if f.options.Semicolons {
+		f.insertAfterPrev(";")
+	}
+}
+
+// reindentComment indents the lines of a multiline comment starting with a
+// star at the given level, aligning them with the first one as in JSDoc
+// comments. Other lines are kept as is.
+func (f *formatter) reindentComment(text string, indent int) string {
+	lines := strings.Split(text, "\n")
+	prefix := strings.Repeat(f.options.Indent, indent)
+	for i := 1; i < len(lines); i++ {
+		if line := strings.TrimLeft(lines[i], " \t"); strings.HasPrefix(line, "*") {
+			lines[i] = prefix + " " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (f *formatter) String() string {
+	var sb strings.Builder
+	for i, line := range f.lines {
+		if len(line) == 0 {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(strings.Repeat(f.options.Indent, f.indents[i]))
+		for _, tok := range line {
+			sb.WriteString(tok.text)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,262 @@
+package forja
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestFormatTypescript(t *testing.T) {
+	tests := []struct {
+		name    string
+		options FormatOptions
+		src     string
+		want    string
+	}{
+		{
+			name: "single quotes",
+			src: `const a = "hello"
+const b = "it's"
+const c = "say \"hi\""
+const d = 'a\'b'
+const e = "<\n"
+`,
+			want: `const a = 'hello'
+const b = "it's"
+const c = 'say "hi"'
+const d = "a'b"
+const e = '<\n'
+`,
+		},
+		{
+			name:    "double quotes",
+			options: FormatOptions{DoubleQuotes: true},
+			src: `const a = 'hello'
+const b = 'say "hi"'
+const c = 'a\'b'
+`,
+			want: `const a = "hello"
+const b = 'say "hi"'
+const c = "a'b"
+`,
+		},
+		{
+			name: "semicolons removed",
+			src: `const a = 1;
+const b = 2
+;[1, 2].forEach(f)
+const c = d;
+(e || f).run()
+`,
+			want: `const a = 1
+const b = 2
+;[1, 2].forEach(f)
+const c = d;
+(e || f).run()
+`,
+		},
+		{
+			name:    "semicolons added",
+			options: FormatOptions{Semicolons: true},
+			src: `const a = 1;
+const b = 2
+function f() {
+  return a
+}
+export type T = {
+  a: string,
+  b: number
+}
+`,
+			want: `const a = 1;
+const b = 2;
+function f() {
+  return a;
+}
+export type T = {
+  a: string;
+  b: number;
+};
+`,
+		},
+		{
+			name: "trailing commas added",
+			src: `const a = {
+  b: 1,
+  c: [
+    1,
+    2
+  ]
+}
+f(
+  a,
+  b
+)
+`,
+			want: `const a = {
+  b: 1,
+  c: [
+    1,
+    2,
+  ],
+}
+f(
+  a,
+  b,
+)
+`,
+		},
+		{
+			name:    "trailing commas removed",
+			options: FormatOptions{NoTrailingCommas: true},
+			src: `const a = {
+  b: 1,
+  c: [
+    1,
+    2,
+  ],
+}
+`,
+			want: `const a = {
+  b: 1,
+  c: [
+    1,
+    2
+  ]
+}
+`,
+		},
+		{
+			name: "comments",
+			src: `export type A = {
+      /**
+   * The name, it's "quoted"
+        */
+  name: string // a 'line' comment
+}
+/* block
+  comment */
+const b = "x" // trailing
+`,
+			want: `export type A = {
+  /**
+   * The name, it's "quoted"
+   */
+  name: string // a 'line' comment
+}
+/* block
+  comment */
+const b = 'x' // trailing
+`,
+		},
+		{
+			name: "template strings",
+			src: "const a = `${b}/'x' \"y\"`\n" +
+				"const c = `multi\n  line ${ {d: \"e\"}.d }`\n",
+			want: "const a = `${b}/'x' \"y\"`\n" +
+				"const c = `multi\n  line ${ {d: \"e\"}.d }`\n",
+		},
+		{
+			name: "regular expressions",
+			src: `const r = s.replace(/'/g, "")
+const x = a / b / c
+const ok = /"[a-z/]+"/i.test(s)
+function f() {
+  return /\//.test(s)
+}
+`,
+			want: `const r = s.replace(/'/g, '')
+const x = a / b / c
+const ok = /"[a-z/]+"/i.test(s)
+function f() {
+  return /\//.test(s)
+}
+`,
+		},
+		{
+			name: "blank lines and empty braces",
+			src: `
+
+
+const a = 1
+
+
+
+const b = {
+
+  c: 1
+
+}
+const e = {
+}
+`,
+			want: `const a = 1
+
+const b = {
+  c: 1,
+}
+const e = {}
+`,
+		},
+		{
+			name:    "tabs",
+			options: FormatOptions{Indent: "\t"},
+			src: `const a = {
+  b: 1
+}
+`,
+			want: "const a = {\n\tb: 1,\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := formatTypescript(test.src, test.options)
+			if got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+			if again := formatTypescript(got, test.options); again != got {
+				t.Errorf("formatting is not idempotent, got\n%s", again)
+			}
+		})
+	}
+}
+
+func TestFormatVariables(t *testing.T) {
+	fj := NewForja(echo.New())
+	fj.AddVariable("messages", map[string]any{
+		"apostrophe": "it's",
+		"quotes":     `say "hi"`,
+		"comment":    "// not a comment /* nor this */",
+		"template":   "`${value}`",
+		"regex":      "/'/g",
+		"html":       "<b>&</b>",
+		"multiline":  "a\nb\tc\\",
+		"not-ident":  1,
+	})
+	fj.AddConstVariable("levels", []string{"don't", `"strict"`})
+
+	var sb strings.Builder
+	fj.printVariables(&sb)
+	got := formatTypescript(sb.String(), FormatOptions{})
+	want := `export const messages = {
+  apostrophe: "it's",
+  comment: '// not a comment /* nor this */',
+  html: '\u003cb\u003e\u0026\u003c/b\u003e',
+  multiline: 'a\nb\tc\\',
+  'not-ident': 1,
+  quotes: 'say "hi"',
+  regex: "/'/g",
+  template: '` + "`${value}`" + `',
+}
+
+export const levels = [
+  "don't",
+  '"strict"',
+] as const
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package forja
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/labstack/echo/v4"
+)
+
+// discardRouter registers no routes, so that handlers can be registered only
+// to generate the client.
+type discardRouter struct{}
+
+func (discardRouter) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return &echo.Route{Method: "POST", Path: path}
+}
+
+// RunGenerator is the entry point of the program built by `forja gen`. It
+// calls register, a function of the application registering its handlers on
+// the given router with the Config of the application, and writes the outputs
+// given as flags:
+//
+//	-out client.ts      the generated client
+//	-outdir client/     the generated client split as by Forja.WriteTsClientDir
+//	-schema schema.json the snapshot returned by Forja.Snapshot
+//	-openapi api.json   the description returned by Forja.OpenAPI
+//	-check              exit with status 1 if the outputs are out of date
+//	                    instead of writing them
+//
+// Outputs are only written when they changed. Handlers are never called, so
+// register does not need to connect to databases or other services.
+func RunGenerator(register func(router Router) *Forja) {
+	flags := flag.NewFlagSet("forja gen", flag.ExitOnError)
+	out := flags.String("out", "", "path of the generated client")
+	outDir := flags.String("outdir", "", "directory of the generated client split in several files")
+	schema := flags.String("schema", "", "path of the schema snapshot")
+	openAPI := flags.String("openapi", "", "path of the OpenAPI description")
+	check := flags.Bool("check", false, "check that the outputs are up to date instead of writing them")
+	_ = flags.Parse(os.Args[1:])
+
+	if *out == "" && *outDir == "" && *schema == "" && *openAPI == "" {
+		fmt.Fprintln(os.Stderr, "forja gen: no output, use -out, -outdir, -schema or -openapi")
+		os.Exit(2)
+	}
+
+	fj := register(discardRouter{})
+
+	outputs := []struct {
+		path     string
+		generate func() ([]byte, error)
+	}{
+		{*out, func() ([]byte, error) { return []byte(fj.GenerateTypescriptClient()), nil }},
+		{*schema, fj.Snapshot},
+		{*openAPI, fj.OpenAPI},
+	}
+
+	stale := false
+	for _, output := range outputs {
+		if output.path == "" {
+			continue
+		}
+		content, err := output.generate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "forja gen: %s: %s\n", output.path, err)
+			os.Exit(2)
+		}
+
+		if *check {
+			current, err := os.ReadFile(output.path)
+			if err != nil || !bytes.Equal(current, content) {
+				fmt.Fprintf(os.Stderr, "forja gen: %s is out of date\n", output.path)
+				stale = true
+			}
+			continue
+		}
+
+		if _, err := writeFileIfChanged(output.path, content); err != nil {
+			fmt.Fprintf(os.Stderr, "forja gen: %s\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if *outDir != "" {
+		files := fj.generateClientFiles()
+		if *check {
+			stale = checkClientFiles(*outDir, files) || stale
+		} else if err := writeClientFiles(*outDir, files); err != nil {
+			fmt.Fprintf(os.Stderr, "forja gen: %s\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if stale {
+		os.Exit(1)
+	}
+}
+
+// checkClientFiles reports the files of dir that differ from the generated
+// files, or that are not generated anymore, and tells whether there are any.
+func checkClientFiles(dir string, files map[string]string) bool {
+	stale := false
+	for _, name := range sortedKeys(files) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		current, err := os.ReadFile(path)
+		if err != nil || string(current) != files[name] {
+			fmt.Fprintf(os.Stderr, "forja gen: %s is out of date\n", path)
+			stale = true
+		}
+	}
+
+	removed, err := staleClientFiles(dir, files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "forja gen: %s\n", err)
+		os.Exit(2)
+	}
+	for _, name := range removed {
+		fmt.Fprintf(os.Stderr, "forja gen: %s should be removed\n", filepath.Join(dir, filepath.FromSlash(name)))
+		stale = true
+	}
+	return stale
+}
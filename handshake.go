@@ -0,0 +1,79 @@
+package forja
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// schemaHeader is sent by the generated client with the hash of the
+	// schema it was generated from.
+	schemaHeader = "X-Forja-Schema"
+	// staleSchemaHeader is set on responses to clients generated from a
+	// different schema than the one of the server.
+	staleSchemaHeader = "X-Forja-Schema-Stale"
+)
+
+// SchemaMismatch is the message of the error returned to stale clients when
+// Config.RejectStaleClients is set.
+const SchemaMismatch = "SCHEMA_MISMATCH"
+
+// SchemaHash returns the hash of the handlers and types of the API, which is
+// embedded in the generated client so that clients generated from a
+// different version of the API, such as SPA tabs left open across a deploy,
+// can be detected.
+func (fj *Forja) SchemaHash() string {
+	// Every request checks the hash, which only locks to compute it
+	if hash := fj.cachedSchemaHash.Load(); hash != nil {
+		return *hash
+	}
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+	return fj.schemaHash()
+}
+
+// schemaHash returns SchemaHash, with fj.mu held.
+func (fj *Forja) schemaHash() string {
+	if hash := fj.cachedSchemaHash.Load(); hash != nil {
+		return *hash
+	}
+	snapshot, err := fj.snapshot()
+	if err != nil {
+		// The schema only contains strings, maps and booleans
+		panic(err)
+	}
+	sum := sha256.Sum256(snapshot)
+	hash := hex.EncodeToString(sum[:8])
+	fj.cachedSchemaHash.Store(&hash)
+	return hash
+}
+
+// resetSchemaHash must be called whenever a registration changes the schema,
+// which changed does.
+func (fj *Forja) resetSchemaHash() {
+	fj.cachedSchemaHash.Store(nil)
+}
+
+// checkSchema compares the schema hash sent by the client with the one of the
+// server. Stale clients get the X-Forja-Schema-Stale header, which the
+// generated client reports to ApiClientConfig.onSchemaMismatch, and are
+// rejected if Config.RejectStaleClients is set. Requests without the header
+// do not come from the generated client and are not checked.
+//
+// Cross-origin clients only see the X-Forja-Schema-Stale header if it is
+// exposed with CORS, see middleware.CORSConfig.ExposeHeaders.
+func (fj *Forja) checkSchema(c echo.Context) error {
+	hash := c.Request().Header.Get(schemaHeader)
+	if hash == "" || hash == fj.SchemaHash() {
+		return nil
+	}
+
+	c.Response().Header().Set(staleSchemaHeader, "true")
+	if fj.config.RejectStaleClients {
+		return echo.NewHTTPError(http.StatusConflict, SchemaMismatch)
+	}
+	return nil
+}
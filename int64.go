@@ -0,0 +1,180 @@
+package forja
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Int64Encoding tells how 64-bit integers are encoded in json. Javascript
+// numbers cannot represent integers above 2^53 exactly, so ids such as
+// snowflakes get corrupted unless they are encoded as strings.
+//
+// It only applies to int64 and uint64 values (and named types of them), but
+// not to time.Duration.
+type Int64Encoding int
+
+const (
+	// Int64AsNumber encodes 64-bit integers as json numbers, like
+	// encoding/json does.
+	Int64AsNumber Int64Encoding = iota
+	// Int64AsString encodes 64-bit integers as json strings, typed as string
+	// in the generated client.
+	Int64AsString
+	// Int64AsBigInt encodes 64-bit integers as json strings, which the
+	// generated client converts from and to bigint.
+	Int64AsBigInt
+)
+
+func is64BitInt(t reflect.Type) bool {
+	return (t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64) && !isDuration(t)
+}
+
+// fieldInt64Encoding returns the encoding of the 64-bit integers of the given
+// field, which can be overridden with the forja tag:
+//
+//	ID int64 `json:"id" forja:"int64=string"`
+func fieldInt64Encoding(field reflect.StructField, defaultEncoding Int64Encoding) Int64Encoding {
+	for _, opt := range strings.Split(field.Tag.Get("forja"), ",") {
+		switch opt {
+		case "int64=number":
+			return Int64AsNumber
+		case "int64=string":
+			return Int64AsString
+		case "int64=bigint":
+			return Int64AsBigInt
+		}
+	}
+	return defaultEncoding
+}
+
+// jsonFieldName returns the name of the field in json, or "" if it is not
+// encoded at all.
+func jsonFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// hasInt64Strings tells whether values of t contain 64-bit integers that are
+// encoded as strings, with the given default encoding.
+func hasInt64Strings(t reflect.Type, defaultEncoding Int64Encoding) bool {
+	return typeWalk[Int64Encoding]{
+		unions: lookupUnion,
+		field: func(field reflect.StructField) (Int64Encoding, fieldMatch) {
+			// encoding/json already encodes quoted fields as strings
+			if isQuoted(field) {
+				return 0, fieldSkip
+			}
+			return fieldInt64Encoding(field, defaultEncoding), fieldWalk
+		},
+		match: func(t reflect.Type, encoding Int64Encoding) bool {
+			return is64BitInt(t) && encoding != Int64AsNumber
+		},
+	}.find(t, defaultEncoding)
+}
+
+// convertInt64s walks a json value decoded with UseNumber from a value of type
+// t, and converts the 64-bit integers encoded as strings from json numbers to
+// strings, or the other way around if toString is false.
+func convertInt64s(node any, t reflect.Type, encoding, defaultEncoding Int64Encoding, toString bool) any {
+	if is64BitInt(t) {
+		if encoding == Int64AsNumber {
+			return node
+		}
+		if number, ok := node.(json.Number); ok && toString {
+			return string(number)
+		}
+		if str, ok := node.(string); ok && !toString {
+			return json.Number(str)
+		}
+		return node
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return convertInt64s(node, t.Elem(), encoding, defaultEncoding, toString)
+	case reflect.Slice, reflect.Array:
+		items, ok := node.([]any)
+		if !ok {
+			return node
+		}
+		for i := range items {
+			items[i] = convertInt64s(items[i], t.Elem(), encoding, defaultEncoding, toString)
+		}
+	case reflect.Map:
+		entries, ok := node.(map[string]any)
+		if !ok {
+			return node
+		}
+		for key := range entries {
+			entries[key] = convertInt64s(entries[key], t.Elem(), encoding, defaultEncoding, toString)
+		}
+	case reflect.Interface:
+		u, ok := lookupUnion(t)
+		entries, isObject := node.(map[string]any)
+		if !ok || !isObject {
+			return node
+		}
+		for _, impl := range u.impls {
+			if entries[u.tag] == u.kindOf(impl) {
+				return convertInt64s(node, impl, encoding, defaultEncoding, toString)
+			}
+		}
+	case reflect.Struct:
+		if isOption(t) || isPatch(t) || isUnion(t) {
+			value, _ := t.FieldByName("Value")
+			return convertInt64s(node, value.Type, encoding, defaultEncoding, toString)
+		}
+		entries, ok := node.(map[string]any)
+		if !ok {
+			return node
+		}
+		convertStructInt64s(entries, t, defaultEncoding, toString)
+	}
+
+	return node
+}
+
+func convertStructInt64s(entries map[string]any, t reflect.Type, defaultEncoding Int64Encoding, toString bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		// Fields of embedded structs are promoted to the parent object
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && embedded.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			convertStructInt64s(entries, embedded, defaultEncoding, toString)
+			continue
+		}
+
+		name := jsonFieldName(field)
+		if name == "" || isQuoted(field) {
+			continue
+		}
+		key, found := name, false
+		if _, found = entries[key]; !found {
+			// encoding/json matches keys case insensitively when decoding
+			for candidate := range entries {
+				if strings.EqualFold(candidate, name) {
+					key, found = candidate, true
+					break
+				}
+			}
+		}
+		if found {
+			encoding := fieldInt64Encoding(field, defaultEncoding)
+			entries[key] = convertInt64s(entries[key], field.Type, encoding, defaultEncoding, toString)
+		}
+	}
+}
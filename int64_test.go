@@ -0,0 +1,75 @@
+package forja
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// postJSON calls the handler on path of e with the json body, and returns the
+// status and body of the response.
+func postJSON(t *testing.T, e *echo.Echo, path, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code, strings.TrimSpace(rec.Body.String())
+}
+
+type int64Item struct {
+	ID       int64   `json:"id"`
+	Count    int64   `json:"count" forja:"int64=number"`
+	Quoted   int64   `json:"quoted,string"`
+	QuotedID *uint64 `json:"quotedId,string"`
+	Small    int32   `json:"small"`
+}
+
+type int64Params struct {
+	Item  int64Item          `json:"item"`
+	IDs   []int64            `json:"ids"`
+	ByKey map[string]uint64  `json:"byKey"`
+	Extra map[string]float64 `json:"extra"`
+}
+
+func echoInt64s(c echo.Context, params int64Params) (int64Params, error) {
+	return params, nil
+}
+
+func TestInt64StringRoundTrip(t *testing.T) {
+	body := `{"item":{"id":"9007199254740993","count":7,"quoted":"11","quotedId":"12","small":3},` +
+		`"ids":["1","18446744073"],"byKey":{"a":"18446744073709551615"},"extra":{"b":1.5}}`
+
+	for _, encoding := range []Int64Encoding{Int64AsString, Int64AsBigInt} {
+		e := echo.New()
+		fj := NewForjaWithConfig(e, Config{Int64Encoding: encoding})
+		AddHandler(fj, echoInt64s)
+
+		status, got := postJSON(t, e, "/forja.echoInt64s", body)
+		if status != http.StatusOK {
+			t.Fatalf("encoding %d: got status %d: %s", encoding, status, got)
+		}
+		assertJSON(t, got, body)
+	}
+}
+
+func TestInt64StringQuotedFields(t *testing.T) {
+	fj := NewForjaWithConfig(echo.New(), Config{Int64Encoding: Int64AsBigInt})
+	AddHandler(fj, echoInt64s)
+	client := fj.GenerateTypescriptClient()
+
+	for _, want := range []string{
+		"  id: bigint\n",
+		"  count: number\n",
+		"  quoted: string\n",
+		"  quotedId: string | null\n",
+		"'github.com/alarbada/forja.int64Item': { object: { id: 'bigint' } }",
+	} {
+		if !strings.Contains(client, want) {
+			t.Errorf("generated client does not contain %q:\n%s", want, client)
+		}
+	}
+}
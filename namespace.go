@@ -0,0 +1,91 @@
+package forja
+
+import (
+	"fmt"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// NestedNaming returns a NamingStrategy that nests namespaces following the
+// package hierarchy below root, so that with NestedNaming("github.com/acme/app")
+// the handlers of github.com/acme/app/api/v1/users are called as
+// client.api.v1.users.Create and served on /api.v1.users.Create. Packages
+// outside of root are nested following their full path.
+//
+// Used as Config.TypeNaming, the dots are replaced with underscores, so that
+// types are named api_v1_users_User.
+func NestedNaming(root string) NamingStrategy {
+	root = strings.TrimSuffix(root, "/")
+	return func(pkgPath string) string {
+		if pkgPath != root {
+			pkgPath = strings.TrimPrefix(pkgPath, root+"/")
+		}
+		segments := strings.Split(pkgPath, "/")
+		for i, segment := range segments {
+			segments[i] = FullPathNaming(segment)
+		}
+		return strings.Join(segments, ".")
+	}
+}
+
+// namespaceTree holds the members of the generated client, where namespaces
+// containing dots, such as api.v1.users, are nested objects.
+type namespaceTree struct {
+	value   string // the typescript value of a handler, empty for namespaces
+	doc     string // the JSDoc of a handler
+	members *orderedmap.OrderedMap[string, *namespaceTree]
+}
+
+func newNamespaceTree() *namespaceTree {
+	return &namespaceTree{members: orderedmap.New[string, *namespaceTree]()}
+}
+
+// add adds a handler in the given namespace, or at the root of t if the
+// namespace is empty.
+func (t *namespaceTree) add(namespace, handlerName, value, doc string) {
+	node := t
+	for _, segment := range strings.Split(namespace, ".") {
+		if segment == "" {
+			continue
+		}
+		child, exists := node.members.Get(segment)
+		if !exists {
+			child = newNamespaceTree()
+			node.members.Set(segment, child)
+		}
+		node = child
+	}
+	node.members.Set(handlerName, &namespaceTree{value: value, doc: doc})
+}
+
+// write writes the members of t as the body of a typescript object.
+func (t *namespaceTree) write(output *strings.Builder, indent string) {
+	for pair := t.members.Oldest(); pair != nil; pair = pair.Next() {
+		name, member := escapeFieldName(pair.Key), pair.Value
+		if member.members == nil {
+			fmt.Fprintf(output, "%s%s%s: %s,\n", jsDoc(member.doc, indent), indent, name, member.value)
+			continue
+		}
+		fmt.Fprintf(output, "%s%s: {\n", indent, name)
+		member.write(output, indent+"  ")
+		fmt.Fprintf(output, "%s},\n", indent)
+	}
+}
+
+// checkNamespaceConflict returns an error if a handler in the given namespace
+// would be generated in the same place as another handler or namespace, such
+// as the handler admin.users and the namespace admin.users.
+func (th *Forja) checkNamespaceConflict(namespace, handlerName string) error {
+	path := namespace + "." + handlerName
+	for pair := th.handlers.Oldest(); pair != nil; pair = pair.Next() {
+		other := pair.Value.namespace + "." + pair.Value.name
+		if strings.HasPrefix(namespace+".", other+".") {
+			return fmt.Errorf("namespace %s conflicts with handler %s", namespace, other)
+		}
+		if strings.HasPrefix(pair.Value.namespace+".", path+".") {
+			return fmt.Errorf("handler %s conflicts with namespace %s", path, pair.Value.namespace)
+		}
+	}
+	return nil
+}
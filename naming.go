@@ -0,0 +1,269 @@
+package forja
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// NamingStrategy returns the name of a Go package in the generated client,
+// used both as the namespace of its handlers and as the prefix of its types.
+type NamingStrategy func(pkgPath string) string
+
+// LastSegmentNaming names packages after the last segment of their path, so
+// that github.com/acme/billing/models is named models. It is the default.
+func LastSegmentNaming(pkgPath string) string {
+	parts := strings.Split(pkgPath, "/")
+	return parts[len(parts)-1]
+}
+
+// FullPathNaming names packages after their full path, so that
+// github.com/acme/billing/models is named github_com_acme_billing_models.
+func FullPathNaming(pkgPath string) string {
+	var sb strings.Builder
+	for _, ch := range pkgPath {
+		if ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_' || ch == '$' {
+			sb.WriteRune(ch)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+// funcPackagePath returns the package path of a function as named by
+// runtime.FuncForPC, such as github.com/acme/api.(*Server).Handler
+func funcPackagePath(funcName string) string {
+	lastSlash := strings.LastIndex(funcName, "/")
+	dot := strings.Index(funcName[lastSlash+1:], ".")
+	if dot == -1 {
+		return funcName
+	}
+	return funcName[:lastSlash+1+dot]
+}
+
+var forjaPkgPath = reflect.TypeFor[Forja]().PkgPath()
+
+// isForjaGeneric tells whether t is an instance of the given generic type
+// declared by forja, such as Option.
+func isForjaGeneric(t reflect.Type, name string) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == forjaPkgPath &&
+		strings.HasPrefix(t.Name(), name+"[")
+}
+
+// typeName returns the name of the named type t in the generated client, such
+// as main_User.
+func (tp *typegen) typeName(t reflect.Type) string {
+	if t.Name() == "" {
+		return ""
+	}
+	if t.PkgPath() == "" {
+		return t.Name()
+	}
+	// Instantiated generic types are all named after their generic type,
+	// Page[main.User] -> main_Page, except ambiguous ones which are generated
+	// once per instantiation, Page[int] -> main_Page_int. See genericType.
+	typeName, args := splitGenericName(t.Name())
+	name := fmt.Sprintf("%s_%s", tp.packageName(t), typeName)
+	if isAmbiguousInstance(t) {
+		for _, arg := range args {
+			name += "_" + typeArgName(arg)
+		}
+	}
+	return name
+}
+
+var (
+	pkgPathPrefix    = regexp.MustCompile(`[\w.~-]+/`)
+	nonIdentifierRun = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// typeArgName turns a type argument into a part of a typescript identifier,
+// []github.com/acme/models.User -> Array_models_User
+func typeArgName(arg string) string {
+	arg = pkgPathPrefix.ReplaceAllString(arg, "")
+	arg = strings.NewReplacer("[]", "Array_", "*", "Ptr_", "map[", "Map_").Replace(arg)
+	return strings.Trim(nonIdentifierRun.ReplaceAllString(arg, "_"), "_")
+}
+
+// packageName returns the name of the package of t prefixing the names of
+// its generated types.
+func (tp *typegen) packageName(t reflect.Type) string {
+	return strings.ReplaceAll(tp.typeNaming(t.PkgPath()), ".", "_")
+}
+
+// isGeneratedType tells whether t is generated as a named type, which makes
+// its name subject to collisions.
+func (tp *typegen) isGeneratedType(t reflect.Type) bool {
+	if t.Name() == "" || t.PkgPath() == "" {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return !isTime(t) && !isOption(t) && !isPatch(t) && !isUnion(t)
+	case reflect.Interface:
+		_, ok := tp.unions[t]
+		return ok
+	default:
+		if _, ok := tp.enumValues(t); ok {
+			return true
+		}
+		return tp.brandedTypes && isBrandableScalar(t) &&
+			!(tp.durationAsMilliseconds && isDuration(t))
+	}
+}
+
+// checkTypeNames walks t and returns an error if any of the types generated
+// for it has the same name as a different type, such as billing/models.User
+// and auth/models.User with LastSegmentNaming. The names of the input views
+// and hoisted anonymous structs of its types are reserved too, so that a
+// main.UserInput type cannot clash with the input view of main.User.
+func (tp *typegen) checkTypeNames(t reflect.Type, visited map[reflect.Type]bool) error {
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+
+	if tp.isGeneratedType(t) {
+		name := tp.typeName(t)
+		// All the instances of a generic type share the same name, unless
+		// they are ambiguous
+		baseName, _ := splitGenericName(t.Name())
+		identity := t.PkgPath() + "." + baseName
+		if isAmbiguousInstance(t) {
+			identity = t.PkgPath() + "." + t.Name()
+		}
+		if other := tp.reserveTypeName(name, identity); other != "" && !strings.HasPrefix(other, "the ") {
+			return fmt.Errorf("types %s and %s are both named %s in the generated client, "+
+				"use a different Config.TypeNaming", other, identity, name)
+		} else if other != "" {
+			return nameClashError(other, identity, name)
+		}
+
+		if tp.viewsDiffer(t) {
+			if err := tp.reserveDerivedName(name+"Input", "the input view of "+identity); err != nil {
+				return err
+			}
+		}
+		// The anonymous structs of generic types are generated inline
+		if t.Kind() == reflect.Struct && (!isGenericType(t) || isAmbiguousInstance(t)) {
+			if err := tp.checkHoistedNames(t, name, name, identity); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return tp.checkTypeNames(t.Elem(), visited)
+	case reflect.Map:
+		if err := tp.checkTypeNames(t.Key(), visited); err != nil {
+			return err
+		}
+		return tp.checkTypeNames(t.Elem(), visited)
+	case reflect.Interface:
+		if u, ok := tp.unions[t]; ok {
+			for _, impl := range u.impls {
+				if err := tp.checkTypeNames(impl, visited); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if err := tp.checkTypeNames(t.Field(i).Type, visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkHoistedNames reserves the names of the anonymous structs in the fields
+// of t, which are generated as types named after the path to them, such as
+// main_User_Address. They are named from name in the output view and from
+// inputName in the input view, see hoistedType.
+func (tp *typegen) checkHoistedNames(t reflect.Type, name, inputName, identity string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := parseTsTag(field)
+		if jsonFieldName(field) == "" || tag.exclude || tag.typ != "" {
+			continue
+		}
+
+		fieldType := field.Type
+		for {
+			if isOption(fieldType) || isPatch(fieldType) {
+				value, _ := fieldType.FieldByName("Value")
+				fieldType = value.Type
+				continue
+			}
+			switch fieldType.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+				fieldType = fieldType.Elem()
+				continue
+			}
+			break
+		}
+		if fieldType.Kind() != reflect.Struct || fieldType.Name() != "" || fieldType.NumField() == 0 {
+			continue
+		}
+
+		fieldName := name + "_" + field.Name
+		fieldInputName := inputName + "_" + field.Name
+		if tp.viewsDiffer(fieldType) {
+			fieldInputName += "Input"
+		}
+		fieldIdentity := "the anonymous struct of " + identity + "." + field.Name
+		if err := tp.reserveDerivedName(fieldName, fieldIdentity); err != nil {
+			return err
+		}
+		if fieldInputName != fieldName {
+			if err := tp.reserveDerivedName(fieldInputName, "the input view of "+fieldIdentity); err != nil {
+				return err
+			}
+		}
+		if err := tp.checkHoistedNames(fieldType, fieldName, fieldInputName, identity+"."+field.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reserveTypeName records that name is generated for the type described by
+// identity. If it is already generated for another one, that one is returned.
+func (tp *typegen) reserveTypeName(name, identity string) string {
+	if other, exists := tp.typeNames[name]; exists && other != identity {
+		return other
+	}
+	tp.typeNames[name] = identity
+	return ""
+}
+
+// reserveDerivedName reserves the name of a type derived from a named type,
+// such as its input view.
+func (tp *typegen) reserveDerivedName(name, identity string) error {
+	if other := tp.reserveTypeName(name, identity); other != "" {
+		return nameClashError(other, identity, name)
+	}
+	return nil
+}
+
+func nameClashError(other, identity, name string) error {
+	return fmt.Errorf("%s and %s are both named %s in the generated client, rename one of them",
+		other, identity, name)
+}
+
+// isIdentifierPath tells whether name is made of javascript identifiers
+// separated by dots, such as users.create
+func isIdentifierPath(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if part == "" || escapeFieldName(part) != part {
+			return false
+		}
+	}
+	return true
+}
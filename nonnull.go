@@ -0,0 +1,196 @@
+package forja
+
+import (
+	"reflect"
+	"strings"
+)
+
+// fieldNonNullCollections tells whether the nil slices and maps of the given
+// field are sent as empty ones, which can be overridden with the forja tag:
+//
+//	Tags []string `json:"tags" forja:"nonnull"`
+//	Tags []string `json:"tags" forja:"nullable"`
+func fieldNonNullCollections(field reflect.StructField, defaultNonNull bool) bool {
+	for _, opt := range strings.Split(field.Tag.Get("forja"), ",") {
+		switch opt {
+		case "nonnull":
+			return true
+		case "nullable":
+			return false
+		}
+	}
+	return defaultNonNull
+}
+
+// hasNonNullCollections tells whether values of t contain slices or maps that
+// are sent as empty ones when nil, unless configured otherwise by their field.
+func hasNonNullCollections(t reflect.Type, defaultNonNull bool) bool {
+	return typeWalk[bool]{
+		unions: lookupUnion,
+		field: func(field reflect.StructField) (bool, fieldMatch) {
+			return fieldNonNullCollections(field, defaultNonNull), fieldWalk
+		},
+		match: func(t reflect.Type, nonNull bool) bool {
+			return nonNull && (t.Kind() == reflect.Slice || t.Kind() == reflect.Map)
+		},
+	}.find(t, defaultNonNull)
+}
+
+// nilFiller replaces the nil slices and maps of values by empty ones, see
+// Config.NonNullCollections. Values are not modified in place, as they may be
+// shared with the handler: the containers leading to a nil collection are
+// copied instead.
+type nilFiller struct {
+	defaultNonNull bool
+	// pointers holds the pointers being walked, as values can be cyclic
+	pointers map[uintptr]bool
+}
+
+func fillNilCollections(value any, defaultNonNull bool) any {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return value
+	}
+	f := nilFiller{defaultNonNull: defaultNonNull, pointers: make(map[uintptr]bool)}
+	if filled, changed := f.fill(v, defaultNonNull); changed {
+		return filled.Interface()
+	}
+	return value
+}
+
+// fill returns v with its nil collections replaced, or v itself if it has
+// none. nonNull tells whether v itself is filled if it is a nil collection.
+func (f nilFiller) fill(v reflect.Value, nonNull bool) (reflect.Value, bool) {
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Ptr:
+		// A nil pointer to a slice is not a nil slice
+		if v.IsNil() || f.pointers[v.Pointer()] {
+			return v, false
+		}
+		f.pointers[v.Pointer()] = true
+		defer delete(f.pointers, v.Pointer())
+		elem, changed := f.fill(v.Elem(), nonNull)
+		if !changed {
+			return v, false
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, true
+
+	case reflect.Slice:
+		if v.IsNil() {
+			if !nonNull {
+				return v, false
+			}
+			return reflect.MakeSlice(t, 0, 0), true
+		}
+		if isByteSlice(t) {
+			return v, false
+		}
+		var filled reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			item, changed := f.fill(v.Index(i), nonNull)
+			if !changed {
+				continue
+			}
+			if !filled.IsValid() {
+				filled = reflect.MakeSlice(t, v.Len(), v.Len())
+				reflect.Copy(filled, v)
+			}
+			filled.Index(i).Set(item)
+		}
+		if !filled.IsValid() {
+			return v, false
+		}
+		return filled, true
+
+	case reflect.Array:
+		var filled reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			item, changed := f.fill(v.Index(i), nonNull)
+			if !changed {
+				continue
+			}
+			if !filled.IsValid() {
+				filled = reflect.New(t).Elem()
+				filled.Set(v)
+			}
+			filled.Index(i).Set(item)
+		}
+		if !filled.IsValid() {
+			return v, false
+		}
+		return filled, true
+
+	case reflect.Map:
+		if v.IsNil() {
+			if !nonNull {
+				return v, false
+			}
+			return reflect.MakeMap(t), true
+		}
+		var filled reflect.Value
+		for iter := v.MapRange(); iter.Next(); {
+			item, changed := f.fill(iter.Value(), nonNull)
+			if !changed {
+				continue
+			}
+			if !filled.IsValid() {
+				filled = reflect.MakeMapWithSize(t, v.Len())
+				for copied := v.MapRange(); copied.Next(); {
+					filled.SetMapIndex(copied.Key(), copied.Value())
+				}
+			}
+			filled.SetMapIndex(iter.Key(), item)
+		}
+		if !filled.IsValid() {
+			return v, false
+		}
+		return filled, true
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := f.fill(v.Elem(), nonNull)
+		if !changed {
+			return v, false
+		}
+		filled := reflect.New(t).Elem()
+		filled.Set(elem)
+		return filled, true
+
+	case reflect.Struct:
+		// The Value of Option, Patch and Union is walked like any field, but
+		// inherits the nullability of their own field
+		wrapper := isOption(t) || isPatch(t) || isUnion(t)
+		var filled reflect.Value
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if jsonFieldName(field) == "" {
+				continue
+			}
+			fieldNonNull := nonNull
+			if !wrapper {
+				fieldNonNull = fieldNonNullCollections(field, f.defaultNonNull)
+			}
+			value, changed := f.fill(v.Field(i), fieldNonNull)
+			if !changed {
+				continue
+			}
+			if !filled.IsValid() {
+				filled = reflect.New(t).Elem()
+				filled.Set(v)
+			}
+			filled.Field(i).Set(value)
+		}
+		if !filled.IsValid() {
+			return v, false
+		}
+		return filled, true
+
+	default:
+		return v, false
+	}
+}
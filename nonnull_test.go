@@ -0,0 +1,99 @@
+package forja
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type nonNullNode struct {
+	Name     string         `json:"name"`
+	Children []*nonNullNode `json:"children"`
+	Parent   *nonNullNode   `json:"-"`
+}
+
+type nonNullResult struct {
+	Zeta     []string          `json:"zeta"`
+	Alpha    map[string]int    `json:"alpha"`
+	Nullable []string          `json:"nullable" forja:"nullable"`
+	Bytes    []byte            `json:"bytes"`
+	Pointer  *[]string         `json:"pointer"`
+	Nested   []nonNullNode     `json:"nested"`
+	ByName   map[string]*[]int `json:"byName"`
+	Option   Option[[]string]  `json:"option"`
+}
+
+func TestFillNilCollections(t *testing.T) {
+	shared := &nonNullNode{Name: "root"}
+	shared.Children = []*nonNullNode{{Name: "child", Parent: shared}}
+	result := nonNullResult{
+		Nested: []nonNullNode{*shared},
+		// A pointer to a nil slice is filled, but a nil pointer is not
+		ByName: map[string]*[]int{"none": nil, "empty": new([]int)},
+	}
+
+	filled := fillNilCollections(result, true).(nonNullResult)
+
+	want := nonNullResult{
+		Zeta:     []string{},
+		Alpha:    map[string]int{},
+		Bytes:    []byte{},
+		Nested:   []nonNullNode{{Name: "root", Children: []*nonNullNode{{Name: "child", Parent: shared, Children: []*nonNullNode{}}}}},
+		ByName:   map[string]*[]int{"none": nil, "empty": {}},
+		Option:   Option[[]string]{Value: []string{}},
+		Nullable: nil,
+	}
+	if !reflect.DeepEqual(filled, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", filled, want)
+	}
+
+	// The result is copied instead of modified
+	if result.Zeta != nil || shared.Children[0].Children != nil {
+		t.Error("the values of the handler were modified")
+	}
+}
+
+func TestFillNilCollectionsUnchanged(t *testing.T) {
+	result := &nonNullResult{
+		Zeta:    []string{"a"},
+		Alpha:   map[string]int{"a": 1},
+		Bytes:   []byte("a"),
+		Nested:  []nonNullNode{},
+		ByName:  map[string]*[]int{},
+		Option:  Option[[]string]{Value: []string{}},
+		Pointer: &[]string{},
+	}
+	if filled := fillNilCollections(result, true); filled != any(result) {
+		t.Errorf("got a copy of a result without nil collections")
+	}
+}
+
+func TestFillNilCollectionsCycle(t *testing.T) {
+	// encoding/json fails on cyclic values, which must not be walked forever
+	node := &nonNullNode{Name: "loop"}
+	node.Children = []*nonNullNode{node}
+	fillNilCollections(node, true)
+}
+
+func getNonNullResult(c echo.Context, params watchGetParams) (nonNullResult, error) {
+	return nonNullResult{}, nil
+}
+
+func TestNonNullCollectionsResponse(t *testing.T) {
+	e := echo.New()
+	fj := NewForjaWithConfig(e, Config{NonNullCollections: true})
+	AddHandler(fj, getNonNullResult)
+
+	status, got := postJSON(t, e, "/forja.getNonNullResult", `{}`)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d: %s", status, got)
+	}
+	// Fields are sent in the order of the struct, as encoding/json does
+	want := `{"zeta":[],"alpha":{},"nullable":null,"bytes":"","pointer":null,"nested":[],"byName":{},` +
+		`"option":{"IsValid":false,"Value":[]}}`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package forja
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// OpenAPI returns an OpenAPI 3.1 description of the API, for tools other than
+// the generated client.
+func (fj *Forja) OpenAPI() ([]byte, error) {
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+
+	s := fj.schema()
+
+	// Types used in both params and results are described once, unless their
+	// views differ, in which case the params one is suffixed with Input.
+	paramsNames := make(map[string]string)
+	resultsNames := make(map[string]string)
+	schemas := map[string]any{
+		"ApiError": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"message": map[string]any{"type": "string"}},
+			"required":   []string{"message"},
+		},
+	}
+	for name := range s.Results {
+		resultsNames[name] = componentName(name)
+	}
+	for name, def := range s.Params {
+		if result, ok := s.Results[name]; ok && !reflect.DeepEqual(def, result) {
+			paramsNames[name] = componentName(name + "Input")
+		} else {
+			paramsNames[name] = componentName(name)
+		}
+	}
+	for name, def := range s.Results {
+		schemas[resultsNames[name]] = openAPIType(def, resultsNames)
+	}
+	for name, def := range s.Params {
+		schemas[paramsNames[name]] = openAPIType(def, paramsNames)
+	}
+
+	paths := make(map[string]any)
+	for path, handler := range s.Handlers {
+		operation := map[string]any{
+			"operationId": strings.TrimPrefix(path, "/"),
+			"requestBody": map[string]any{
+				"required": true,
+				"content":  jsonContent(openAPIRef(handler.paramsRef, paramsNames)),
+			},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     jsonContent(openAPIRef(handler.resultRef, resultsNames)),
+				},
+				"400": map[string]any{
+					"description": "Error",
+					"content":     jsonContent(map[string]any{"$ref": "#/components/schemas/ApiError"}),
+				},
+			},
+		}
+		if handler.Deprecated {
+			operation["deprecated"] = true
+		}
+		paths[path] = map[string]any{"post": operation}
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"openapi":    "3.1.0",
+		"info":       map[string]any{"title": "forja", "version": fj.schemaHash()},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}, "", "  ")
+}
+
+func jsonContent(schema any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// componentName returns a valid component name for a type, whose name may
+// contain the dots of anonymous structs or the brackets of generic types.
+func componentName(name string) string {
+	var sb strings.Builder
+	for _, ch := range name {
+		if ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_' || ch == '-' || ch == '.' {
+			sb.WriteRune(ch)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	return strings.TrimRight(sb.String(), "_")
+}
+
+func openAPIType(def schemaType, names map[string]string) map[string]any {
+	switch def.Kind {
+	case "enum":
+		values := make([]any, len(def.Values))
+		for i, literal := range def.Values {
+			_ = json.Unmarshal([]byte(literal), &values[i])
+		}
+		return map[string]any{"enum": values}
+	case "union":
+		kinds := make([]string, 0, len(def.Variants))
+		for kind := range def.Variants {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		variants := make([]any, len(kinds))
+		for i, kind := range kinds {
+			variants[i] = map[string]any{"allOf": []any{
+				map[string]any{
+					"type":       "object",
+					"properties": map[string]any{def.Tag: map[string]any{"const": kind}},
+					"required":   []string{def.Tag},
+				},
+				map[string]any{"$ref": "#/components/schemas/" + names[def.Variants[kind]]},
+			}}
+		}
+		return map[string]any{"oneOf": variants}
+	}
+
+	properties := make(map[string]any)
+	required := []string{}
+	for name, field := range def.Fields {
+		property := openAPIRef(field.ref, names)
+		if field.Nullable {
+			property = nullableSchema(property)
+		}
+		properties[name] = property
+		if !field.Optional {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+func openAPIRef(ref *typeRef, names map[string]string) map[string]any {
+	var schema map[string]any
+	switch ref.kind {
+	case "string", "number", "boolean":
+		schema = map[string]any{"type": ref.kind}
+	case "time":
+		schema = map[string]any{"type": "string", "format": "date-time"}
+	case "bytes":
+		schema = map[string]any{"type": "string", "contentEncoding": "base64"}
+	case "named":
+		schema = map[string]any{"$ref": "#/components/schemas/" + names[ref.name]}
+	case "array":
+		schema = map[string]any{"type": "array", "items": openAPIRef(ref.elem, names)}
+	case "tuple":
+		schema = map[string]any{
+			"type":     "array",
+			"items":    openAPIRef(ref.elem, names),
+			"minItems": ref.len,
+			"maxItems": ref.len,
+		}
+	case "record":
+		schema = map[string]any{"type": "object", "additionalProperties": openAPIRef(ref.elem, names)}
+	case "ts":
+		schema = map[string]any{"description": "typescript: " + ref.name}
+	default:
+		schema = map[string]any{}
+	}
+
+	if ref.nullable {
+		return nullableSchema(schema)
+	}
+	return schema
+}
+
+func nullableSchema(schema map[string]any) map[string]any {
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
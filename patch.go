@@ -0,0 +1,153 @@
+package forja
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+type PatchState int
+
+const (
+	// PatchAbsent means that the field was not sent
+	PatchAbsent PatchState = iota
+	// PatchNull means that the field was explicitly sent as null
+	PatchNull
+	// PatchValue means that the field was sent with a value
+	PatchValue
+)
+
+// Patch is like Option, but it also tells whether the field was sent as null
+// or not sent at all. Use it in the params of partial update handlers, where
+// absent fields are left untouched and null fields are cleared. See
+// ApplyPatch.
+type Patch[T any] struct {
+	State PatchState
+	Value T
+}
+
+// Valid tells whether the patch holds a value.
+func (p Patch[T]) Valid() bool {
+	return p.State == PatchValue
+}
+
+func (p Patch[T]) IsNull() bool {
+	return p.State == PatchNull
+}
+
+func (p Patch[T]) IsAbsent() bool {
+	return p.State == PatchAbsent
+}
+
+func (p Patch[T]) MarshalJSON() ([]byte, error) {
+	if p.State == PatchValue {
+		return json.Marshal(p.Value)
+	}
+	return json.Marshal(nil)
+}
+
+// UnmarshalJSON is only called for fields present in the json object, so
+// absent fields keep the PatchAbsent zero value.
+func (p *Patch[T]) UnmarshalJSON(data []byte) error {
+	var zero T
+	p.Value = zero
+	if string(data) == "null" {
+		p.State = PatchNull
+		return nil
+	}
+	p.State = PatchValue
+	return json.Unmarshal(data, &p.Value)
+}
+
+func (p Patch[T]) patchState() PatchState {
+	return p.State
+}
+
+func (p Patch[T]) patchValue() reflect.Value {
+	return reflect.ValueOf(&p.Value).Elem()
+}
+
+type patchField interface {
+	patchState() PatchState
+	patchValue() reflect.Value
+}
+
+// ApplyPatch applies every Patch field of patch onto the field of dst with the
+// same name. Absent fields are skipped, null fields are set to their zero value
+// and fields with a value are set to it. Fields of dst can be of type T, *T or
+// Option[T]. Fields of patch that are not a Patch are ignored.
+//
+//	type UpdateUserParams struct {
+//		ID   UserID
+//		Name forja.Patch[string]
+//		Bio  forja.Patch[string]
+//	}
+//
+//	user := db.GetUser(params.ID)
+//	err := forja.ApplyPatch(&user, params)
+func ApplyPatch[D any, P any](dst *D, patch P) error {
+	dstValue := reflect.ValueOf(dst).Elem()
+	patchValue := reflect.ValueOf(patch)
+	for patchValue.Kind() == reflect.Ptr {
+		patchValue = patchValue.Elem()
+	}
+	if dstValue.Kind() != reflect.Struct || patchValue.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot apply patch %s onto %s, both must be structs",
+			patchValue.Type(), dstValue.Type())
+	}
+
+	patchType := patchValue.Type()
+	for i := 0; i < patchType.NumField(); i++ {
+		if !patchType.Field(i).IsExported() {
+			continue
+		}
+		field, ok := patchValue.Field(i).Interface().(patchField)
+		if !ok {
+			continue
+		}
+
+		name := patchType.Field(i).Name
+		dstField := dstValue.FieldByName(name)
+		if !dstField.IsValid() || !dstField.CanSet() {
+			return fmt.Errorf("cannot apply patch field %s onto %s, it has no such field",
+				name, dstValue.Type())
+		}
+
+		if err := applyPatchField(dstField, field); err != nil {
+			return fmt.Errorf("cannot apply patch field %s onto %s: %w", name, dstValue.Type(), err)
+		}
+	}
+
+	return nil
+}
+
+func applyPatchField(dst reflect.Value, field patchField) error {
+	value := field.patchValue()
+	switch {
+	case dst.Type() == value.Type():
+	case dst.Kind() == reflect.Ptr && dst.Type().Elem() == value.Type():
+	case isOption(dst.Type()) && dst.FieldByName("Value").Type() == value.Type():
+	default:
+		return fmt.Errorf("%s is not assignable to %s", value.Type(), dst.Type())
+	}
+
+	switch field.patchState() {
+	case PatchAbsent:
+	case PatchNull:
+		dst.SetZero()
+	case PatchValue:
+		switch {
+		case dst.Type() == value.Type():
+			dst.Set(value)
+		case dst.Kind() == reflect.Ptr:
+			ptr := reflect.New(value.Type())
+			ptr.Elem().Set(value)
+			dst.Set(ptr)
+		default:
+			dst.FieldByName("IsValid").SetBool(true)
+			dst.FieldByName("Value").Set(value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package forja
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type patchUser struct {
+	Name     string
+	Bio      *string
+	Nickname Option[string]
+	Age      int
+}
+
+type patchUserParams struct {
+	Name     Patch[string] `json:"name"`
+	Bio      Patch[string] `json:"bio"`
+	Nickname Patch[string] `json:"nickname"`
+	Age      int           `json:"age"`
+}
+
+func TestPatchStates(t *testing.T) {
+	tests := []struct {
+		body  string
+		state PatchState
+		value string
+	}{
+		{`{}`, PatchAbsent, ""},
+		{`{"name":null}`, PatchNull, ""},
+		{`{"name":"Ada"}`, PatchValue, "Ada"},
+		{`{"name":""}`, PatchValue, ""},
+	}
+	for _, test := range tests {
+		var params patchUserParams
+		if err := json.Unmarshal([]byte(test.body), &params); err != nil {
+			t.Fatal(err)
+		}
+		if params.Name.State != test.state || params.Name.Value != test.value {
+			t.Errorf("%s: got state %d and value %q, want state %d and value %q",
+				test.body, params.Name.State, params.Name.Value, test.state, test.value)
+		}
+	}
+}
+
+func TestPatchMarshal(t *testing.T) {
+	params := patchUserParams{
+		Name: Patch[string]{State: PatchValue, Value: "Ada"},
+		Bio:  Patch[string]{State: PatchNull, Value: "ignored"},
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"name":"Ada","bio":null,"nickname":null,"age":0}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	bio := "Mathematician"
+	user := patchUser{Name: "Ada", Bio: &bio, Nickname: Option[string]{IsValid: true, Value: "Countess"}, Age: 36}
+
+	var params patchUserParams
+	if err := json.Unmarshal([]byte(`{"name":"Ada Lovelace","bio":null,"age":37}`), &params); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyPatch(&user, params); err != nil {
+		t.Fatal(err)
+	}
+
+	// Age is not a Patch, and nickname was not sent
+	want := patchUser{Name: "Ada Lovelace", Nickname: Option[string]{IsValid: true, Value: "Countess"}, Age: 36}
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("got %+v, want %+v", user, want)
+	}
+
+	var next patchUserParams
+	if err := json.Unmarshal([]byte(`{"bio":"Poet","nickname":"Enchantress"}`), &next); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyPatch(&user, &next); err != nil {
+		t.Fatal(err)
+	}
+	if user.Bio == nil || *user.Bio != "Poet" || user.Nickname != (Option[string]{IsValid: true, Value: "Enchantress"}) {
+		t.Errorf("got bio %v and nickname %+v", user.Bio, user.Nickname)
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	var user patchUser
+	tests := []struct {
+		name  string
+		patch any
+		want  string
+	}{
+		{"missing field", struct{ Email Patch[string] }{}, "it has no such field"},
+		{"mismatched type", struct{ Age Patch[string] }{}, "string is not assignable to int"},
+		{"not a struct", 1, "both must be structs"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ApplyPatch(&user, test.patch)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func updatePatchUser(c echo.Context, params patchUserParams) (*patchUser, error) {
+	user := &patchUser{Name: "Ada", Age: 36}
+	err := ApplyPatch(user, params)
+	return user, err
+}
+
+func TestPatchHandler(t *testing.T) {
+	e := echo.New()
+	fj := NewForja(e)
+	AddHandler(fj, updatePatchUser)
+
+	status, got := postJSON(t, e, "/forja.updatePatchUser", `{"name":null,"bio":"Poet"}`)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d: %s", status, got)
+	}
+	assertJSON(t, got, `{"Name":"","Bio":"Poet","Nickname":null,"Age":36}`)
+}
@@ -0,0 +1,123 @@
+package forja
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Some features cannot be implemented with encoding/json alone. Nil slices
+// and maps are sent as empty ones (see Config.NonNullCollections) by replacing
+// them in results before they are encoded. 64-bit integers are encoded as
+// strings (see Int64Encoding) by decoding json into a generic tree that is
+// rewritten following the Go type it was encoded from, or is going to be
+// decoded into.
+
+// jsonRewrites tells which rewrites the json of values of a given type needs
+type jsonRewrites struct {
+	int64Strings              bool
+	nonNullCollections        bool
+	int64Encoding             Int64Encoding
+	defaultNonNullCollections bool
+}
+
+func newJSONRewrites(t reflect.Type, config Config) jsonRewrites {
+	return jsonRewrites{
+		int64Strings:              hasInt64Strings(t, config.Int64Encoding),
+		nonNullCollections:        hasNonNullCollections(t, config.NonNullCollections),
+		int64Encoding:             config.Int64Encoding,
+		defaultNonNullCollections: config.NonNullCollections,
+	}
+}
+
+// handlerRewrites holds the rewrites of the params and result of a handler
+type handlerRewrites struct {
+	params, result jsonRewrites
+}
+
+// rewrites returns the rewrites of the handler entry. They depend on the
+// unions its types use, which may be registered after the handler, so they
+// are computed by its first request and again after every AddUnion.
+func (fj *Forja) rewrites(entry *handlerEntry) *handlerRewrites {
+	if r := entry.rewrites.Load(); r != nil {
+		return r
+	}
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+	if r := entry.rewrites.Load(); r != nil {
+		return r
+	}
+	r := &handlerRewrites{
+		params: newJSONRewrites(entry.handlerType.In(1), fj.config),
+		result: newJSONRewrites(entry.handlerType.Out(0), fj.config),
+	}
+	entry.rewrites.Store(r)
+	return r
+}
+
+func decodeTree(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var node any
+	if err := decoder.Decode(&node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// fillResult replaces the nil slices and maps of the result of a handler by
+// empty ones, if its type has any to replace.
+func (r jsonRewrites) fillResult(result any) any {
+	if !r.nonNullCollections {
+		return result
+	}
+	return fillNilCollections(result, r.defaultNonNullCollections)
+}
+
+// encodeResult encodes the result of a handler, of type t, as json with its
+// 64-bit integers as strings.
+func (r jsonRewrites) encodeResult(result any, t reflect.Type) ([]byte, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	node, err := decodeTree(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(convertInt64s(node, t, r.int64Encoding, r.int64Encoding, true))
+}
+
+// rewriteParams replaces the request body, sent for params of type t, so that
+// it can be bound by encoding/json.
+func (r jsonRewrites) rewriteParams(c echo.Context, t reflect.Type) error {
+	if !r.int64Strings {
+		return nil
+	}
+
+	req := c.Request()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		return nil
+	}
+
+	node, err := decodeTree(body)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(convertInt64s(node, t, r.int64Encoding, r.int64Encoding, false))
+	if err != nil {
+		return err
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	return nil
+}
@@ -0,0 +1,78 @@
+package forja
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type rewriteEvent interface{ isRewriteEvent() }
+
+type rewriteCreated struct {
+	ID   int64    `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+func (rewriteCreated) isRewriteEvent() {}
+
+type rewriteDeleted struct {
+	IDs []uint64 `json:"ids"`
+	By  *int64   `json:"by,omitempty"`
+}
+
+func (*rewriteDeleted) isRewriteEvent() {}
+
+type rewriteEventParams struct {
+	Event  Union[rewriteEvent]   `json:"event"`
+	Events []Union[rewriteEvent] `json:"events" forja:"nullable"`
+}
+
+func echoRewriteEvent(c echo.Context, params rewriteEventParams) (rewriteEventParams, error) {
+	return params, nil
+}
+
+func TestRewritesUnionRegisteredAfterHandler(t *testing.T) {
+	e := echo.New()
+	fj := NewForjaWithConfig(e, Config{Int64Encoding: Int64AsString, NonNullCollections: true})
+	AddHandler(fj, echoRewriteEvent)
+	AddUnion[rewriteEvent](fj, "kind", rewriteCreated{}, &rewriteDeleted{})
+
+	status, got := postJSON(t, e, "/forja.echoRewriteEvent", `{"event":{"kind":"rewriteCreated","id":"9007199254740993"}}`)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d: %s", status, got)
+	}
+	assertJSON(t, got, `{"event":{"kind":"rewriteCreated","id":"9007199254740993","tags":[]},"events":null}`)
+}
+
+func TestRewritesRoundTrip(t *testing.T) {
+	e := echo.New()
+	fj := NewForjaWithConfig(e, Config{Int64Encoding: Int64AsString, NonNullCollections: true})
+	AddUnion[rewriteEvent](fj, "kind", rewriteCreated{}, &rewriteDeleted{})
+	AddHandler(fj, echoRewriteEvent)
+
+	body := `{"event":{"kind":"rewriteDeleted","ids":["18446744073709551615"],"by":"-9007199254740993"},` +
+		`"events":[{"kind":"rewriteCreated","id":"1","tags":["a"]},{"kind":"rewriteDeleted","ids":[]},null]}`
+	status, got := postJSON(t, e, "/forja.echoRewriteEvent", body)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d: %s", status, got)
+	}
+	assertJSON(t, got, body)
+}
+
+// assertJSON fails unless got and want are the same json value.
+func assertJSON(t *testing.T, got, want string) {
+	t.Helper()
+	gotTree, err := decodeTree([]byte(got))
+	if err != nil {
+		t.Fatalf("invalid json %s: %s", got, err)
+	}
+	wantTree, err := decodeTree([]byte(want))
+	if err != nil {
+		t.Fatalf("invalid json %s: %s", want, err)
+	}
+	if !reflect.DeepEqual(gotTree, wantTree) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,469 @@
+package forja
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// schema is the description of the handlers and types of an API written by
+// Snapshot. Types are described separately for params and results, as
+// changes that break clients differ between both.
+type schema struct {
+	Handlers map[string]schemaHandler `json:"handlers"`
+	Params   map[string]schemaType    `json:"params"`
+	Results  map[string]schemaType    `json:"results"`
+}
+
+type schemaHandler struct {
+	Params     string `json:"params"`
+	Result     string `json:"result"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+
+	paramsRef, resultRef *typeRef
+}
+
+// schemaType describes a named type, which is either an object, an enum or a
+// union.
+type schemaType struct {
+	Kind     string                 `json:"kind"`
+	Fields   map[string]schemaField `json:"fields,omitempty"`
+	Values   []string               `json:"values,omitempty"`
+	Tag      string                 `json:"tag,omitempty"`
+	Variants map[string]string      `json:"variants,omitempty"`
+}
+
+type schemaField struct {
+	Type     string `json:"type"`
+	Optional bool   `json:"optional,omitempty"`
+	Nullable bool   `json:"nullable,omitempty"`
+
+	ref *typeRef
+}
+
+// typeRef is the json type of a value, written in snapshots as string,
+// main_User[] or Record<string, number> | null for example.
+type typeRef struct {
+	// kind is one of string, number, boolean, time, bytes, unknown, named,
+	// array, tuple, record, or ts for types set with the ts tag.
+	kind string
+	// name is the name of named types, or the typescript type of ts
+	name string
+	// elem is the element type of arrays, tuples and records, and key the key
+	// type of records.
+	elem, key *typeRef
+	len       int
+	nullable  bool
+}
+
+func (r *typeRef) String() string {
+	var s string
+	switch r.kind {
+	case "named", "ts":
+		s = r.name
+	case "array", "tuple":
+		elem := r.elem.String()
+		if r.elem.nullable {
+			elem = "(" + elem + ")"
+		}
+		if r.kind == "array" {
+			s = elem + "[]"
+		} else {
+			s = fmt.Sprintf("[%d]%s", r.len, elem)
+		}
+	case "record":
+		s = fmt.Sprintf("Record<%s, %s>", r.key, r.elem)
+	default:
+		s = r.kind
+	}
+	if r.nullable {
+		s += " | null"
+	}
+	return s
+}
+
+// Snapshot returns a canonical json description of all the handlers and types
+// of the API, to be compared with the one of a previous release with Diff.
+func (fj *Forja) Snapshot() ([]byte, error) {
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+	return fj.snapshot()
+}
+
+// snapshot returns Snapshot, with fj.mu held.
+func (fj *Forja) snapshot() ([]byte, error) {
+	return json.MarshalIndent(fj.schema(), "", "  ")
+}
+
+func (fj *Forja) schema() *schema {
+	s := &schema{
+		Handlers: make(map[string]schemaHandler),
+		Params:   make(map[string]schemaType),
+		Results:  make(map[string]schemaType),
+	}
+	params := &schemaBuilder{tp: fj.typegen, types: s.Params, input: true}
+	results := &schemaBuilder{tp: fj.typegen, types: s.Results}
+
+	for pair := fj.handlers.Oldest(); pair != nil; pair = pair.Next() {
+		entry := pair.Value
+		paramsRef := params.ref(entry.handlerType.In(1),
+			camelcaseNames(entry.namespace, entry.name, "Input"), fj.config.Int64Encoding, fj.config.NonNullCollections)
+		resultRef := results.ref(resultType(entry.handlerType),
+			camelcaseNames(entry.namespace, entry.name, "Output"), fj.config.Int64Encoding, fj.config.NonNullCollections)
+		s.Handlers[pair.Key] = schemaHandler{
+			Params:     paramsRef.String(),
+			Result:     resultRef.String(),
+			Deprecated: entry.deprecation != nil,
+			paramsRef:  paramsRef,
+			resultRef:  resultRef,
+		}
+	}
+	return s
+}
+
+// schemaBuilder describes the types reachable from params, if input is set,
+// or from results.
+type schemaBuilder struct {
+	tp    *typegen
+	types map[string]schemaType
+	input bool
+}
+
+// ref returns the json type of t, describing named types in types. Anonymous
+// structs are named after their parent, main_User.address for example.
+func (b *schemaBuilder) ref(t reflect.Type, name string, encoding Int64Encoding, nonNull bool) *typeRef {
+	if values, ok := b.tp.enumValues(t); ok {
+		return &typeRef{kind: "named", name: b.enum(t, values)}
+	}
+	if is64BitInt(t) {
+		if encoding == Int64AsNumber {
+			return &typeRef{kind: "number"}
+		}
+		return &typeRef{kind: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		switch {
+		case isTime(t):
+			return &typeRef{kind: "time"}
+		case isOption(t) || isPatch(t):
+			value, _ := t.FieldByName("Value")
+			return b.ref(value.Type, name, encoding, nonNull)
+		case isUnion(t):
+			return b.ref(t.Field(0).Type, name, encoding, nonNull)
+		}
+		if t.Name() != "" {
+			name = b.tp.typeName(t)
+			if isGenericType(t) && !isAmbiguousInstance(t) {
+				_, args := splitGenericName(t.Name())
+				name += "[" + strings.Join(args, ", ") + "]"
+			}
+		}
+		return &typeRef{kind: "named", name: b.object(t, name)}
+	case reflect.Interface:
+		if u, ok := b.tp.unions[t]; ok {
+			return &typeRef{kind: "named", name: b.union(t, u, encoding, nonNull)}
+		}
+		return &typeRef{kind: "unknown"}
+	case reflect.Ptr:
+		return b.ref(t.Elem(), name, encoding, nonNull)
+	case reflect.Slice:
+		if t == reflect.TypeFor[json.RawMessage]() {
+			return &typeRef{kind: "unknown"}
+		}
+		if isByteSlice(t) {
+			return &typeRef{kind: "bytes", nullable: !nonNull}
+		}
+		return &typeRef{kind: "array", elem: b.ref(t.Elem(), name, encoding, nonNull), nullable: !nonNull}
+	case reflect.Array:
+		return &typeRef{kind: "tuple", elem: b.ref(t.Elem(), name, encoding, nonNull), len: t.Len()}
+	case reflect.Map:
+		return &typeRef{
+			kind:     "record",
+			key:      b.ref(t.Key(), name, encoding, nonNull),
+			elem:     b.ref(t.Elem(), name, encoding, nonNull),
+			nullable: !nonNull,
+		}
+	case reflect.String:
+		return &typeRef{kind: "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Float32, reflect.Float64, reflect.Int64, reflect.Uint64:
+		return &typeRef{kind: "number"}
+	case reflect.Bool:
+		return &typeRef{kind: "boolean"}
+	default:
+		return &typeRef{kind: "unknown"}
+	}
+}
+
+func (b *schemaBuilder) object(t reflect.Type, name string) string {
+	if _, exists := b.types[name]; exists {
+		return name
+	}
+	def := schemaType{Kind: "object", Fields: make(map[string]schemaField)}
+	// Registered before its fields, for recursive types
+	b.types[name] = def
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldName := jsonFieldName(field)
+		tag := parseTsTag(field)
+		if fieldName == "" || tag.exclude {
+			continue
+		}
+
+		ref := &typeRef{kind: "ts", name: tag.typ}
+		if tag.typ == "" && isQuoted(field) {
+			ref = &typeRef{kind: "string"}
+		} else if tag.typ == "" {
+			ref = b.ref(field.Type, name+"."+fieldName,
+				fieldInt64Encoding(field, b.tp.defaultInt64Encoding),
+				fieldNonNullCollections(field, b.tp.defaultNonNullCollections))
+		}
+		optional, nullable := fieldPresence(field, tag, b.input)
+		def.Fields[fieldName] = schemaField{Type: ref.String(), Optional: optional, Nullable: nullable, ref: ref}
+	}
+	return name
+}
+
+func (b *schemaBuilder) enum(t reflect.Type, values []any) string {
+	name := b.tp.typeName(t)
+	if _, exists := b.types[name]; exists {
+		return name
+	}
+	def := schemaType{Kind: "enum"}
+	for _, value := range values {
+		literal, _ := json.Marshal(value)
+		def.Values = append(def.Values, string(literal))
+	}
+	sort.Strings(def.Values)
+	b.types[name] = def
+	return name
+}
+
+func (b *schemaBuilder) union(iface reflect.Type, u *union, encoding Int64Encoding, nonNull bool) string {
+	name := b.tp.typeName(iface)
+	if _, exists := b.types[name]; exists {
+		return name
+	}
+	def := schemaType{Kind: "union", Tag: u.tag, Variants: make(map[string]string)}
+	b.types[name] = def
+	for _, impl := range u.impls {
+		def.Variants[u.kindOf(impl)] = b.ref(impl, name+"."+u.kindOf(impl), encoding, nonNull).String()
+	}
+	return name
+}
+
+// Change is a difference between two snapshots of an API.
+type Change struct {
+	// Breaking tells whether clients built against the old API may fail
+	// with the new one.
+	Breaking bool
+	// Path locates the change, such as /main.getUser or main_User.name
+	Path        string
+	Description string
+}
+
+func (c Change) String() string {
+	if c.Breaking {
+		return fmt.Sprintf("breaking: %s: %s", c.Path, c.Description)
+	}
+	return fmt.Sprintf("compatible: %s: %s", c.Path, c.Description)
+}
+
+// Diff compares two snapshots written by Snapshot and returns their
+// differences, sorted by path. It can be used from a test to detect breaking
+// changes against the snapshot of the last release:
+//
+//	old, _ := os.ReadFile("api.snapshot.json")
+//	new, _ := fj.Snapshot()
+//	changes, err := forja.Diff(old, new)
+//	for _, change := range changes {
+//		if change.Breaking {
+//			t.Error(change)
+//		}
+//	}
+func Diff(old, new []byte) ([]Change, error) {
+	var oldSchema, newSchema schema
+	if err := json.Unmarshal(old, &oldSchema); err != nil {
+		return nil, fmt.Errorf("invalid old snapshot: %w", err)
+	}
+	if err := json.Unmarshal(new, &newSchema); err != nil {
+		return nil, fmt.Errorf("invalid new snapshot: %w", err)
+	}
+
+	var changes []Change
+	add := func(breaking bool, path, format string, args ...any) {
+		changes = append(changes, Change{Breaking: breaking, Path: path, Description: fmt.Sprintf(format, args...)})
+	}
+
+	for path, oldHandler := range oldSchema.Handlers {
+		newHandler, exists := newSchema.Handlers[path]
+		if !exists {
+			add(true, path, "handler removed")
+			continue
+		}
+		if oldHandler.Params != newHandler.Params {
+			add(true, path, "params changed from %s to %s", oldHandler.Params, newHandler.Params)
+		}
+		if oldHandler.Result != newHandler.Result {
+			add(true, path, "result changed from %s to %s", oldHandler.Result, newHandler.Result)
+		}
+		if !oldHandler.Deprecated && newHandler.Deprecated {
+			add(false, path, "handler deprecated")
+		}
+	}
+	for path := range newSchema.Handlers {
+		if _, exists := oldSchema.Handlers[path]; !exists {
+			add(false, path, "handler added")
+		}
+	}
+
+	diffTypes(oldSchema.Params, newSchema.Params, true, add)
+	diffTypes(oldSchema.Results, newSchema.Results, false, add)
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].Description < changes[j].Description
+	})
+
+	// Types used in both params and results are compared twice, and breaking
+	// if either change is
+	merged := changes[:0]
+	for _, change := range changes {
+		if n := len(merged); n > 0 && merged[n-1].Path == change.Path && merged[n-1].Description == change.Description {
+			merged[n-1].Breaking = merged[n-1].Breaking || change.Breaking
+			continue
+		}
+		merged = append(merged, change)
+	}
+	return merged, nil
+}
+
+// diffTypes compares the types used in params, if input is set, or in results.
+// Params may lose fields and gain optional fields and enum values, and results
+// may lose optional fields and gain fields, without breaking older clients.
+func diffTypes(old, new map[string]schemaType, input bool, add func(bool, string, string, ...any)) {
+	view := "results"
+	if input {
+		view = "params"
+	}
+
+	for name, oldType := range old {
+		newType, exists := new[name]
+		if !exists {
+			// Handlers using it have changed too
+			add(true, name, "type removed from %s", view)
+			continue
+		}
+		if oldType.Kind != newType.Kind {
+			add(true, name, "changed from %s to %s", oldType.Kind, newType.Kind)
+			continue
+		}
+
+		switch oldType.Kind {
+		case "object":
+			diffFields(name, oldType.Fields, newType.Fields, input, add)
+		case "enum":
+			oldValues, newValues := valueSet(oldType.Values), valueSet(newType.Values)
+			for value := range oldValues {
+				if !newValues[value] {
+					add(true, name, "enum value %s removed", value)
+				}
+			}
+			for value := range newValues {
+				if !oldValues[value] {
+					// Older clients do not expect it in results
+					add(!input, name, "enum value %s added", value)
+				}
+			}
+		case "union":
+			if oldType.Tag != newType.Tag {
+				add(true, name, "tag changed from %s to %s", oldType.Tag, newType.Tag)
+			}
+			for kind := range oldType.Variants {
+				if _, exists := newType.Variants[kind]; !exists {
+					add(true, name, "variant %s removed", kind)
+				}
+			}
+			for kind := range newType.Variants {
+				if _, exists := oldType.Variants[kind]; !exists {
+					add(!input, name, "variant %s added", kind)
+				}
+			}
+		}
+	}
+}
+
+func diffFields(typeName string, old, new map[string]schemaField, input bool, add func(bool, string, string, ...any)) {
+	var removed, added []string
+	for name := range old {
+		if _, exists := new[name]; !exists {
+			removed = append(removed, name)
+		}
+	}
+	for name := range new {
+		if _, exists := old[name]; !exists {
+			added = append(added, name)
+		}
+	}
+
+	// Older clients send removed params, which are ignored, and may expect
+	// removed results unless they were optional
+	removalBreaks := func(name string) bool {
+		return !input && !old[name].Optional
+	}
+	// Older clients do not send required params
+	additionBreaks := func(name string) bool {
+		return input && !new[name].Optional
+	}
+
+	// A field replaced by another one of the same type was most likely renamed
+	if len(removed) == 1 && len(added) == 1 && old[removed[0]] == new[added[0]] {
+		add(removalBreaks(removed[0]) || additionBreaks(added[0]), typeName+"."+removed[0], "field renamed to %s", added[0])
+		removed, added = nil, nil
+	}
+	for _, name := range removed {
+		add(removalBreaks(name), typeName+"."+name, "field removed")
+	}
+	for _, name := range added {
+		add(additionBreaks(name), typeName+"."+name, "field added")
+	}
+
+	for name, oldField := range old {
+		newField, exists := new[name]
+		if !exists {
+			continue
+		}
+		path := typeName + "." + name
+		if oldField.Type != newField.Type {
+			add(true, path, "type changed from %s to %s", oldField.Type, newField.Type)
+		}
+		switch {
+		case oldField.Optional && !newField.Optional:
+			add(input, path, "optional field made required")
+		case !oldField.Optional && newField.Optional:
+			add(!input, path, "required field made optional")
+		}
+		switch {
+		case oldField.Nullable && !newField.Nullable:
+			add(input, path, "nullable field made non-nullable")
+		case !oldField.Nullable && newField.Nullable:
+			add(!input, path, "non-nullable field made nullable")
+		}
+	}
+}
+
+func valueSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,212 @@
+package forja
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func objectType(fields map[string]schemaField) schemaType {
+	return schemaType{Kind: "object", Fields: fields}
+}
+
+func marshalSchema(t *testing.T, s schema) []byte {
+	t.Helper()
+	if s.Handlers == nil {
+		s.Handlers = map[string]schemaHandler{}
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDiff(t *testing.T) {
+	handler := schemaHandler{Params: "main_Params", Result: "main_User"}
+
+	tests := []struct {
+		name     string
+		old, new schema
+		want     []Change
+	}{
+		{
+			name: "handler removed",
+			old:  schema{Handlers: map[string]schemaHandler{"/main.getUser": handler}},
+			new:  schema{},
+			want: []Change{{Breaking: true, Path: "/main.getUser", Description: "handler removed"}},
+		},
+		{
+			name: "handler added",
+			old:  schema{},
+			new:  schema{Handlers: map[string]schemaHandler{"/main.getUser": handler}},
+			want: []Change{{Breaking: false, Path: "/main.getUser", Description: "handler added"}},
+		},
+		{
+			name: "handler deprecated",
+			old:  schema{Handlers: map[string]schemaHandler{"/main.getUser": handler}},
+			new: schema{Handlers: map[string]schemaHandler{"/main.getUser": {
+				Params: handler.Params, Result: handler.Result, Deprecated: true,
+			}}},
+			want: []Change{{Breaking: false, Path: "/main.getUser", Description: "handler deprecated"}},
+		},
+		{
+			name: "handler result changed",
+			old:  schema{Handlers: map[string]schemaHandler{"/main.getUser": handler}},
+			new: schema{Handlers: map[string]schemaHandler{"/main.getUser": {
+				Params: handler.Params, Result: "main_Account",
+			}}},
+			want: []Change{{Breaking: true, Path: "/main.getUser", Description: "result changed from main_User to main_Account"}},
+		},
+		{
+			name: "param field removed",
+			old:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"id": {Type: "string"}, "name": {Type: "string"}})}},
+			new:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			want: []Change{{Breaking: false, Path: "main_Params.name", Description: "field removed"}},
+		},
+		{
+			name: "required result field removed",
+			old:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}, "name": {Type: "string"}})}},
+			new:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			want: []Change{{Breaking: true, Path: "main_User.name", Description: "field removed"}},
+		},
+		{
+			name: "optional result field removed",
+			old:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}, "name": {Type: "string", Optional: true}})}},
+			new:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			want: []Change{{Breaking: false, Path: "main_User.name", Description: "field removed"}},
+		},
+		{
+			name: "required param field added",
+			old:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			new:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"id": {Type: "string"}, "name": {Type: "string"}})}},
+			want: []Change{{Breaking: true, Path: "main_Params.name", Description: "field added"}},
+		},
+		{
+			name: "optional param field added",
+			old:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			new:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"id": {Type: "string"}, "name": {Type: "string", Optional: true}})}},
+			want: []Change{{Breaking: false, Path: "main_Params.name", Description: "field added"}},
+		},
+		{
+			name: "result field added",
+			old:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			new:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}, "name": {Type: "string"}})}},
+			want: []Change{{Breaking: false, Path: "main_User.name", Description: "field added"}},
+		},
+		{
+			name: "required param field renamed",
+			old:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"name": {Type: "string"}})}},
+			new:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"fullName": {Type: "string"}})}},
+			want: []Change{{Breaking: true, Path: "main_Params.name", Description: "field renamed to fullName"}},
+		},
+		{
+			name: "optional result field renamed",
+			old:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"name": {Type: "string", Optional: true}})}},
+			new:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"fullName": {Type: "string", Optional: true}})}},
+			want: []Change{{Breaking: false, Path: "main_User.name", Description: "field renamed to fullName"}},
+		},
+		{
+			name: "field type changed",
+			old:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			new:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "number"}})}},
+			want: []Change{{Breaking: true, Path: "main_User.id", Description: "type changed from string to number"}},
+		},
+		{
+			name: "param field made required and nullable",
+			old:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"name": {Type: "string", Optional: true}})}},
+			new:  schema{Params: map[string]schemaType{"main_Params": objectType(map[string]schemaField{"name": {Type: "string", Nullable: true}})}},
+			want: []Change{
+				{Breaking: false, Path: "main_Params.name", Description: "non-nullable field made nullable"},
+				{Breaking: true, Path: "main_Params.name", Description: "optional field made required"},
+			},
+		},
+		{
+			name: "result field made optional and non-nullable",
+			old:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"name": {Type: "string", Nullable: true}})}},
+			new:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"name": {Type: "string", Optional: true}})}},
+			want: []Change{
+				{Breaking: false, Path: "main_User.name", Description: "nullable field made non-nullable"},
+				{Breaking: true, Path: "main_User.name", Description: "required field made optional"},
+			},
+		},
+		{
+			name: "enum values changed in params",
+			old:  schema{Params: map[string]schemaType{"main_Status": {Kind: "enum", Values: []string{`"active"`, `"archived"`}}}},
+			new:  schema{Params: map[string]schemaType{"main_Status": {Kind: "enum", Values: []string{`"active"`, `"deleted"`}}}},
+			want: []Change{
+				{Breaking: true, Path: "main_Status", Description: `enum value "archived" removed`},
+				{Breaking: false, Path: "main_Status", Description: `enum value "deleted" added`},
+			},
+		},
+		{
+			name: "enum value added in results",
+			old:  schema{Results: map[string]schemaType{"main_Status": {Kind: "enum", Values: []string{`"active"`}}}},
+			new:  schema{Results: map[string]schemaType{"main_Status": {Kind: "enum", Values: []string{`"active"`, `"deleted"`}}}},
+			want: []Change{{Breaking: true, Path: "main_Status", Description: `enum value "deleted" added`}},
+		},
+		{
+			name: "union variants changed",
+			old: schema{
+				Params:  map[string]schemaType{"main_Shape": {Kind: "union", Tag: "kind", Variants: map[string]string{"Circle": "main_Circle"}}},
+				Results: map[string]schemaType{"main_Event": {Kind: "union", Tag: "kind", Variants: map[string]string{"Created": "main_Created"}}},
+			},
+			new: schema{
+				Params:  map[string]schemaType{"main_Shape": {Kind: "union", Tag: "kind", Variants: map[string]string{"Circle": "main_Circle", "Square": "main_Square"}}},
+				Results: map[string]schemaType{"main_Event": {Kind: "union", Tag: "kind", Variants: map[string]string{"Created": "main_Created", "Deleted": "main_Deleted"}}},
+			},
+			want: []Change{
+				{Breaking: true, Path: "main_Event", Description: "variant Deleted added"},
+				{Breaking: false, Path: "main_Shape", Description: "variant Square added"},
+			},
+		},
+		{
+			name: "type removed from results",
+			old:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			new:  schema{},
+			want: []Change{{Breaking: true, Path: "main_User", Description: "type removed from results"}},
+		},
+		{
+			name: "type used in params and results",
+			old: schema{
+				Params:  map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}})},
+				Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}})},
+			},
+			new: schema{
+				Params:  map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}, "name": {Type: "string"}})},
+				Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}, "name": {Type: "string"}})},
+			},
+			want: []Change{{Breaking: true, Path: "main_User.name", Description: "field added"}},
+		},
+		{
+			name: "unchanged",
+			old:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			new:  schema{Results: map[string]schemaType{"main_User": objectType(map[string]schemaField{"id": {Type: "string"}})}},
+			want: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes, err := Diff(marshalSchema(t, test.old), marshalSchema(t, test.new))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changes) == 0 && len(test.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(changes, test.want) {
+				t.Errorf("got changes\n  %v\nwant\n  %v", changes, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffInvalidSnapshot(t *testing.T) {
+	if _, err := Diff([]byte("{"), marshalSchema(t, schema{})); err == nil {
+		t.Error("expected an error for an invalid old snapshot")
+	}
+	if _, err := Diff(marshalSchema(t, schema{}), []byte("{")); err == nil {
+		t.Error("expected an error for an invalid new snapshot")
+	}
+}
@@ -1,5 +1,4 @@
 // AUTOGENERATED, DO NOT EDIT
-
 export const API_VERSION = '1.0.0'
 
 export const MAX_RETRIES = 3
@@ -12,7 +11,11 @@ export const DEFAULT_USER = {
     created: '2023-03-14T15:09:26Z',
 }
 
-export const SUPPORTED_FORMATS = ['json', 'xml', 'yaml']
+export const SUPPORTED_FORMATS = [
+    'json',
+    'xml',
+    'yaml',
+]
 
 export const SAMPLE_PLAYLISTS = [
     {
@@ -42,43 +45,64 @@ export interface ApiError {
     message: string
     statusCode?: number
 }
-export type ApiResponse<T> = { data: T; error: null } | { data: null; error: ApiError }
+export type ApiResponse<T> =
+    | { data: T; error: null }
+    | { data: null; error: ApiError }
 
-type MainExampleHandler1Handler = (
-    params: main_ExampleParams,
-) => Promise<ApiResponse<main_ExampleResponse>>
-type MainExampleHandler2Handler = (
-    params: main_ExampleParams,
-) => Promise<ApiResponse<main_ExampleResponse>>
+type MainExampleHandler1Handler = (params: main_ExampleParams) => Promise<ApiResponse<main_ExampleResponse>>
+type MainExampleHandler2Handler = (params: main_ExampleParams) => Promise<ApiResponse<main_ExampleResponse>>
 type MainHelloWorldHandler = () => Promise<ApiResponse<main_HelloWorldOutput>>
-type MainGetPlaylistsHandler = () => Promise<ApiResponse<main_Playlist>>
+type MainGetPlaylistsHandler = () => Promise<ApiResponse<(main_Playlist[] | null)>>
+type MainGetPlaylistsPageHandler = () => Promise<ApiResponse<main_Page<main_Playlist>>>
+type MainUpdatePlaylistHandler = (params: main_updatePlaylistInputInput) => Promise<ApiResponse<main_Playlist>>
 type MainExampleWithExternalTypesHandler = (params: pkg_Type2) => Promise<ApiResponse<pkg_Type1>>
 type MainTheHandlerHandler = () => Promise<ApiResponse<{}>>
 type MainTheHandlerPtrHandler = () => Promise<ApiResponse<{}>>
 type MainCircularHandler = (params: {}) => Promise<ApiResponse<main_Node>>
-type MainWeHandleInputPointersHandler = (
-    params: main_PointersAreUndefined,
-) => Promise<ApiResponse<main_weHandleInputPointersOutput>>
-type MainWeAlsoHandleEnumsHandler = (
-    params: main_EnumLike,
-) => Promise<ApiResponse<main_weAlsoHandleEnumsResult>>
+type MainWeHandleInputPointersHandler = (params: main_PointersAreUndefinedInput) => Promise<ApiResponse<main_weHandleInputPointersOutput>>
+type MainWeAlsoHandleEnumsHandler = (params: main_EnumLikeInput) => Promise<ApiResponse<main_weAlsoHandleEnumsResult>>
+type MainSetPlaylistStatusHandler = (params: main_setPlaylistStatusInput) => Promise<ApiResponse<{}>>
+type MainGetPlaylistItemsHandler = () => Promise<ApiResponse<main_getPlaylistItemsOutput>>
 type PkgSomeHandlerHandler = () => Promise<ApiResponse<pkg_SomeHandlerRes>>
+type AdminPlaylistsGetHandler = (params: main_getPlaylistInput) => Promise<ApiResponse<main_Playlist>>
+type PlaylistServiceGetHandler = (params: main_getPlaylistInput) => Promise<ApiResponse<main_Playlist>>
+type PlaylistServiceListHandler = () => Promise<ApiResponse<(main_Playlist[] | null)>>
 export type ApiClient = {
     main: {
         ExampleHandler1: MainExampleHandler1Handler
+        /**
+         * ExampleHandler2 greets the user like ExampleHandler1.
+         *
+         * @deprecated use ExampleHandler1 instead.
+         */
         ExampleHandler2: MainExampleHandler2Handler
         HelloWorld: MainHelloWorldHandler
         getPlaylists: MainGetPlaylistsHandler
+        getPlaylistsPage: MainGetPlaylistsPageHandler
+        /** Fields not sent are left untouched, and fields sent as null are cleared. */
+        updatePlaylist: MainUpdatePlaylistHandler
         ExampleWithExternalTypes: MainExampleWithExternalTypesHandler
         theHandler: MainTheHandlerHandler
         theHandlerPtr: MainTheHandlerPtrHandler
         circular: MainCircularHandler
         weHandleInputPointers: MainWeHandleInputPointersHandler
         weAlsoHandleEnums: MainWeAlsoHandleEnumsHandler
+        /** Unknown statuses are rejected by forja before the handler is called. */
+        setPlaylistStatus: MainSetPlaylistStatusHandler
+        getPlaylistItems: MainGetPlaylistItemsHandler
     }
     pkg: {
         SomeHandler: PkgSomeHandlerHandler
     }
+    admin: {
+        playlists: {
+            get: AdminPlaylistsGetHandler
+        }
+    }
+    playlistService: {
+        Get: PlaylistServiceGetHandler
+        List: PlaylistServiceListHandler
+    }
 }
 export type main_User = {
     name: string
@@ -87,20 +111,33 @@ export type main_User = {
 }
 export type main_ExampleParams = {
     name: string
-    users: main_User[] | null
+    users: (main_User[] | null)
 }
 export type main_ExampleResponse = {
     greeting: string
+    /** @deprecated use greeting */
+    message: string
 }
 export type main_HelloWorldOutput = {
     result: string
 }
+/** Playlist is an ordered list of songs. */
 export type main_Playlist = {
+    id?: string
+    playlistId?: string
+    title?: string
+    /** Pinned playlists are listed first */
+    pinned?: boolean
+    description?: string
+}
+export type main_Page<T> = {
+    items: (T[] | null)
+    total: number
+}
+export type main_updatePlaylistInputInput = {
     id: string
-    playlistId: string
-    title: string
-    pinned: boolean
-    description: string
+    title?: string | null
+    description?: string | null
 }
 export type pkg_Type1 = {
     Name: string
@@ -110,40 +147,91 @@ export type pkg_Type2 = {
     Type: pkg_Type1
 }
 export type main_Node = {
-    Children: main_Node[] | null
+    Children: (main_Node[] | null)
 }
-export type main_PointersAreUndefined = {
-    APtr?: string
-    AnotherPtr?: {
-        name: string
-    }
+export type main_PointersAreUndefined_AnotherPtr = {
+    Name: string
+}
+/** PointersAreUndefined, so that we don't need to fill them in our typescript definitions */
+export type main_PointersAreUndefinedInput = {
+    APtr?: string | null
+    AnotherPtr?: main_PointersAreUndefined_AnotherPtr | null
 }
 export type main_weHandleInputPointersOutput = {
     APtrIsUndefined: boolean
     AnotherPtrIsUndefined: boolean
 }
-export type main_EnumLike = {
-    Opt1?: string
-    Opt2?: {
-        name: string
-        age: number
-    }
+export type main_EnumLike_Opt2 = {
+    Name: string
+    Age: number
+}
+/**
+ * Yeah, ik, golang does not have enums, so this is the best I can think of to
+ * encode enums. I cannot think of a way to make the tag approach (have an int
+ * or string indicating which option is valid) work, as we cannot obtain all possible
+ * tag values by reflection.
+ */
+export type main_EnumLikeInput = {
+    Opt1?: string | null
+    Opt2?: main_EnumLike_Opt2 | null
 }
 export type main_weAlsoHandleEnumsResult = {
     Opt1WasFilled: boolean
     Opt2WasFilled: boolean
 }
+export type main_PlaylistStatus = 'active' | 'archived'
+export const main_PlaylistStatusValues = ['active', 'archived'] as const
+export type main_Visibility = 0 | 1
+export const main_VisibilityValues = [0, 1] as const
+export type main_setPlaylistStatusInput = {
+    playlistId: string
+    status: main_PlaylistStatus
+    visibility: main_Visibility
+}
+export type main_Song = {
+    title: string
+    artist: string
+}
+export type main_Podcast = {
+    title: string
+    episode: number
+}
+/** PlaylistItem is one of the shapes that can be added to a playlist */
+export type main_PlaylistItem =
+    | ({ kind: 'Song' } & main_Song)
+    | ({ kind: 'Podcast' } & main_Podcast)
+export type main_getPlaylistItemsOutput = {
+    items: (main_PlaylistItem[] | null)
+}
 export type pkg_SomeHandlerReq = {}
 export type pkg_SomeHandlerRes = {}
+export type main_getPlaylistInput = {
+    id: string
+}
+
+export const SCHEMA_HASH = '6ac563ee4418e07e'
 
 export type ApiClientConfig = {
     beforeRequest?: (config: RequestInit) => void | Promise<void>
+    /**
+     * Called when the server runs a different version of the API than the one
+     * this client was generated from, to prompt a reload for example
+     */
+    onSchemaMismatch?: () => void
 }
 
 export const REQUEST_ABORTED = 'REQUEST_ABORTED'
+export const SCHEMA_MISMATCH = 'SCHEMA_MISMATCH'
 
-export function createApiClient(baseUrl: string, config?: ApiClientConfig): ApiClient {
-    async function doFetch(path: string, params?: unknown) {
+export function createApiClient(
+    baseUrl: string,
+    config?: ApiClientConfig,
+): ApiClient {
+    async function doFetch(
+        path: string,
+        params?: unknown,
+        decode?: (data: unknown) => unknown,
+    ) {
         try {
             if (params === undefined) {
                 params = {}
@@ -153,6 +241,7 @@ export function createApiClient(baseUrl: string, config?: ApiClientConfig): ApiC
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
+                    'X-Forja-Schema': SCHEMA_HASH,
                 },
                 body: JSON.stringify(params ?? {}),
             }
@@ -162,6 +251,9 @@ export function createApiClient(baseUrl: string, config?: ApiClientConfig): ApiC
             }
 
             const response = await fetch(`${baseUrl}/${path}`, requestConfig)
+            if (response.headers.get('X-Forja-Schema-Stale') !== null) {
+                config?.onSchemaMismatch?.()
+            }
             if (!response.ok) {
                 const data = await response.json()
                 const message = data.message
@@ -172,7 +264,7 @@ export function createApiClient(baseUrl: string, config?: ApiClientConfig): ApiC
                 }
             }
             const data = await response.json()
-            return { data, error: null }
+            return { data: decode ? decode(data) : data, error: null }
         } catch (error) {
             if (error instanceof DOMException && error.name === 'AbortError') {
                 return {
@@ -185,7 +277,8 @@ export function createApiClient(baseUrl: string, config?: ApiClientConfig): ApiC
             return {
                 data: null,
                 error: {
-                    message: error instanceof Error ? error.message : 'Unknown error occurred',
+                    message:
+                        error instanceof Error ? error.message : 'Unknown error occurred',
                 },
             }
         }
@@ -196,16 +289,29 @@ export function createApiClient(baseUrl: string, config?: ApiClientConfig): ApiC
             ExampleHandler2: (params) => doFetch('main.ExampleHandler2', params),
             HelloWorld: () => doFetch('main.HelloWorld'),
             getPlaylists: () => doFetch('main.getPlaylists'),
+            getPlaylistsPage: () => doFetch('main.getPlaylistsPage'),
+            updatePlaylist: (params) => doFetch('main.updatePlaylist', params),
             ExampleWithExternalTypes: (params) => doFetch('main.ExampleWithExternalTypes', params),
             theHandler: () => doFetch('main.theHandler'),
             theHandlerPtr: () => doFetch('main.theHandlerPtr'),
             circular: (params) => doFetch('main.circular', params),
             weHandleInputPointers: (params) => doFetch('main.weHandleInputPointers', params),
             weAlsoHandleEnums: (params) => doFetch('main.weAlsoHandleEnums', params),
+            setPlaylistStatus: (params) => doFetch('main.setPlaylistStatus', params),
+            getPlaylistItems: () => doFetch('main.getPlaylistItems'),
         },
         pkg: {
             SomeHandler: () => doFetch('pkg.SomeHandler'),
         },
+        admin: {
+            playlists: {
+                get: (params) => doFetch('admin.playlists.get', params),
+            },
+        },
+        playlistService: {
+            Get: (params) => doFetch('playlistService.Get', params),
+            List: () => doFetch('playlistService.List'),
+        },
     }
     return client
 }
@@ -1,6 +1,6 @@
 // this is some example usage of how to use the generated apiclient
 
-import { createApiClient, main_PointersAreUndefined } from './apiclient'
+import { createApiClient, main_PointersAreUndefinedInput } from './apiclient'
 
 const apiclient = createApiClient('http://localhost:8080', {
     beforeRequest(config) {
@@ -30,7 +30,7 @@ console.log('getPlaylists:', await apiclient.main.getPlaylists())
 console.log('theHandler:', await apiclient.main.theHandler())
 console.log('theHandlerPtr:', await apiclient.main.theHandlerPtr())
 
-let ptrs: main_PointersAreUndefined = {}
+let ptrs: main_PointersAreUndefinedInput = {}
 console.log('weHandleInputPointers:', await apiclient.main.weHandleInputPointers(ptrs))
 
 console.log(
@@ -41,6 +41,6 @@ console.log(
 console.log(
     'weAlsoHandleEnums opt 2 result',
     await apiclient.main.weAlsoHandleEnums({
-        Opt2: { name: 'john salchichon', age: 28 },
+        Opt2: { Name: 'john salchichon', Age: 28 },
     }),
 )
@@ -0,0 +1,54 @@
+package forja
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ClientRouter is the router ServeClient mounts its endpoints on, such as
+// *echo.Echo or *echo.Group.
+type ClientRouter interface {
+	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// ServeClient serves the generated client and the description of the API, so
+// that a frontend living in another repository can pull them from a running
+// dev or staging server:
+//
+//   - /_forja/client.ts, the client written by WriteTsClient
+//   - /_forja/schema.json, the snapshot returned by Snapshot
+//   - /_forja/openapi.json, the description returned by OpenAPI
+//
+// Responses have an ETag, so that unchanged files are not downloaded again.
+// The endpoints are public unless protected by the given middleware, which
+// should be done outside of local development:
+//
+//	fj.ServeClient(e, middleware.KeyAuth(validateKey))
+func (fj *Forja) ServeClient(router ClientRouter, middleware ...echo.MiddlewareFunc) {
+	serve := func(contentType string, generate func() ([]byte, error)) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			body, err := generate()
+			if err != nil {
+				return err
+			}
+
+			sum := sha256.Sum256(body)
+			etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+			c.Response().Header().Set("ETag", etag)
+			c.Response().Header().Set("Cache-Control", "no-cache")
+			if c.Request().Header.Get("If-None-Match") == etag {
+				return c.NoContent(http.StatusNotModified)
+			}
+			return c.Blob(http.StatusOK, contentType, body)
+		}
+	}
+
+	router.GET("/_forja/client.ts", serve("text/plain; charset=utf-8", func() ([]byte, error) {
+		return []byte(fj.GenerateTypescriptClient()), nil
+	}), middleware...)
+	router.GET("/_forja/schema.json", serve(echo.MIMEApplicationJSON, fj.Snapshot), middleware...)
+	router.GET("/_forja/openapi.json", serve(echo.MIMEApplicationJSON, fj.OpenAPI), middleware...)
+}
@@ -0,0 +1,106 @@
+package forja
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	echoContextType = reflect.TypeFor[echo.Context]()
+	errorType       = reflect.TypeFor[error]()
+)
+
+// AddService registers every exported method of svc with the signature of a
+// Handler, in a namespace named after the type of svc.
+//
+//	forja.AddService(fj, &UserService{})
+//
+// serves UserService.Create on /userService.Create. The namespace can be set
+// with forja.Namespace, and forja.Deprecated deprecates every method. Methods
+// taking an echo.Context that are not valid handlers make AddService panic, as
+// they are most likely a mistake.
+func AddService(th *Forja, svc any, opts ...HandlerOption) {
+	var options handlerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.name != "" {
+		panic("AddService: forja.Name cannot be used with a service, its handlers are named after its methods")
+	}
+
+	value := reflect.ValueOf(svc)
+	if !value.IsValid() {
+		panic("AddService: service is nil")
+	}
+	svcType := value.Type()
+	structType := svcType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Name() == "" {
+		panic(fmt.Sprintf("AddService: %s is not a named type", svcType))
+	}
+	if options.namespace == "" {
+		name := structType.Name()
+		options.namespace = strings.ToLower(name[:1]) + name[1:]
+	}
+
+	var methods []reflect.Method
+	var invalid []string
+	for i := 0; i < svcType.NumMethod(); i++ {
+		method := svcType.Method(i)
+		methodType := method.Type // includes the receiver
+		if methodType.NumIn() < 2 || methodType.In(1) != echoContextType {
+			continue
+		}
+		if err := checkHandlerType(value.Method(i).Type()); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %s", method.Name, err))
+			continue
+		}
+		methods = append(methods, method)
+	}
+
+	if len(invalid) > 0 {
+		panic(fmt.Sprintf("AddService: methods of %s are not valid handlers, "+
+			"expected func(echo.Context, P) (R, error):\n  %s", svcType, strings.Join(invalid, "\n  ")))
+	}
+	if len(methods) == 0 {
+		panic(fmt.Sprintf("AddService: %s has no exported handler methods", svcType))
+	}
+
+	for _, method := range methods {
+		fn := value.Method(method.Index)
+		methodOptions := handlerOptions{name: method.Name, namespace: options.namespace}
+		if d := options.deprecation; d != nil {
+			// Every method counts its own deprecated calls
+			methodOptions.deprecation = &deprecation{message: d.message, since: d.since, sunset: d.sunset}
+		}
+		fullName := fmt.Sprintf("%s.%s.%s", structType.PkgPath(), structType.Name(), method.Name)
+		file, _ := runtime.FuncForPC(method.Func.Pointer()).FileLine(method.Func.Pointer())
+		th.register("AddService", fullName, file, methodOptions, fn.Type(), func(c echo.Context, params any) (any, error) {
+			out := fn.Call([]reflect.Value{reflect.ValueOf(&c).Elem(), reflect.ValueOf(params).Elem()})
+			err, _ := out[1].Interface().(error)
+			return out[0].Interface(), err
+		})
+	}
+}
+
+// checkHandlerType returns an error if t, which takes an echo.Context, does not
+// have the signature of a Handler.
+func checkHandlerType(t reflect.Type) error {
+	switch {
+	case t.IsVariadic():
+		return fmt.Errorf("is variadic")
+	case t.NumIn() != 2:
+		return fmt.Errorf("takes %d arguments", t.NumIn())
+	case t.NumOut() != 2:
+		return fmt.Errorf("returns %d values", t.NumOut())
+	case t.Out(1) != errorType:
+		return fmt.Errorf("returns %s instead of error", t.Out(1))
+	}
+	return nil
+}
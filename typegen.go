@@ -1,9 +1,12 @@
 package forja
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
 	orderedmap "github.com/wk8/go-ordered-map/v2"
 )
@@ -11,13 +14,132 @@ import (
 type typegen struct {
 	typeDefs        *orderedmap.OrderedMap[string, string]
 	processingTypes map[string]bool
+
+	// typePackages holds the package of every type in typeDefs, which is the
+	// file it is written to by WriteTsClientDir.
+	typePackages map[string]string
+
+	// brandedTypes turns named string and integer types into branded
+	// typescript types, see Config.BrandedTypes
+	brandedTypes bool
+
+	// enums caches the *enumSet of every type looked up by enumValues, nil
+	// for types that are not enums. It is read by concurrent requests.
+	enums sync.Map
+
+	// unions holds the interfaces registered with AddUnion
+	unions map[reflect.Type]*union
+
+	// typeParams maps the type arguments of the generic type being generated
+	// to its type parameters, and typeArgs collects the types found for each
+	// parameter.
+	typeParams map[string]string
+	typeArgs   map[string]reflect.Type
+
+	// genericArgTypes holds the types found for the type parameters of every
+	// instantiated generic type, see genericType.
+	genericArgTypes map[string][]reflect.Type
+
+	// input is set while generating the types of handler params. Types whose
+	// input view differs from their output view are generated twice, the
+	// input one being suffixed with "Input", see InputType.
+	input bool
+
+	// See Config.TimeAsDate and Config.DurationAsMilliseconds
+	timeAsDate             bool
+	durationAsMilliseconds bool
+
+	// codecDefs holds the codecs of named types, see codec.go, and codecsUsed
+	// tells whether any value has to be converted by the client at all.
+	codecDefs  map[string]string
+	codecsUsed bool
+
+	// int64Encoding is the encoding of the 64-bit integers being generated,
+	// which is defaultInt64Encoding unless overridden by the field being
+	// generated, see fieldInt64Encoding.
+	int64Encoding        Int64Encoding
+	defaultInt64Encoding Int64Encoding
+
+	// nonNullCollections tells whether the slices and maps being generated
+	// are sent as empty ones when nil, see fieldNonNullCollections.
+	nonNullCollections        bool
+	defaultNonNullCollections bool
+
+	// anonName is the name given to the anonymous struct being generated,
+	// derived from the named type or handler that contains it and the path of
+	// fields to it, see hoistedType. anonTypes holds the types already
+	// generated for each name.
+	anonName  string
+	anonTypes map[string]reflect.Type
+	// anonInNamed is set when anonName is derived from a named type, which
+	// is generated in both views.
+	anonInNamed bool
+	// anonPackage is the package of the anonymous struct being generated,
+	// the one of the named type or handler that contains it.
+	anonPackage string
+
+	// typeNaming names the packages of types, and typeNames holds the type
+	// behind every generated name, see checkTypeNames.
+	typeNaming NamingStrategy
+	typeNames  map[string]string
+
+	// bytesUsed tells whether any []byte was generated, so that the base64
+	// helpers are needed.
+	bytesUsed bool
+
+	// docs holds the Go doc comments emitted as JSDoc, nil unless
+	// Config.Docs is set.
+	docs *docIndex
 }
 
-func newTypegen() *typegen {
-	return &typegen{
-		typeDefs:        orderedmap.New[string, string](),
-		processingTypes: make(map[string]bool),
+func newTypegen(config Config) *typegen {
+	typeNaming := config.TypeNaming
+	if typeNaming == nil {
+		typeNaming = LastSegmentNaming
 	}
+	var docs *docIndex
+	if config.Docs {
+		docs = newDocIndex()
+	}
+
+	tp := &typegen{
+		brandedTypes: config.BrandedTypes,
+		unions:       make(map[reflect.Type]*union),
+
+		timeAsDate:             config.TimeAsDate,
+		durationAsMilliseconds: config.DurationAsMilliseconds,
+
+		int64Encoding:        config.Int64Encoding,
+		defaultInt64Encoding: config.Int64Encoding,
+
+		nonNullCollections:        config.NonNullCollections,
+		defaultNonNullCollections: config.NonNullCollections,
+
+		typeNaming: typeNaming,
+		typeNames:  make(map[string]string),
+
+		docs: docs,
+	}
+	tp.reset()
+	return tp
+}
+
+// reset forgets the types generated so far, keeping the registered ones, so
+// that every generation of the client is the same as a first one.
+func (tp *typegen) reset() {
+	tp.typeDefs = orderedmap.New[string, string]()
+	tp.processingTypes = make(map[string]bool)
+	tp.typePackages = make(map[string]string)
+	tp.anonTypes = make(map[string]reflect.Type)
+	tp.genericArgTypes = make(map[string][]reflect.Type)
+	tp.codecDefs = make(map[string]string)
+	tp.codecsUsed, tp.bytesUsed = false, false
+}
+
+// setTypeDef registers the definition of the type name of the given package.
+func (tp *typegen) setTypeDef(name, pkg, def string) {
+	tp.typeDefs.Set(name, def)
+	tp.typePackages[name] = pkg
 }
 
 func (tp *typegen) printTypeDefs(sb *strings.Builder) {
@@ -27,17 +149,140 @@ func (tp *typegen) printTypeDefs(sb *strings.Builder) {
 	}
 }
 
-func getFullTypeName(t reflect.Type) string {
-	if t.Name() == "" {
-		return ""
+func isGenericType(t reflect.Type) bool {
+	return t.PkgPath() != "" && strings.HasSuffix(t.Name(), "]")
+}
+
+// isAmbiguousInstance tells whether t is an instantiated generic type with a
+// type argument that is not a type declared in a package, or that appears
+// twice in the type arguments or the fields of t, whose occurrences cannot be
+// told apart from the fields of the same type, see genericType.
+func isAmbiguousInstance(t reflect.Type) bool {
+	if !isGenericType(t) {
+		return false
 	}
-	if t.PkgPath() == "" {
-		return t.Name()
+	_, args := splitGenericName(t.Name())
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		if seen[arg] || !strings.Contains(arg, ".") {
+			return true
+		}
+		seen[arg] = true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, arg := range args {
+		count := 0
+		for i := 0; i < t.NumField(); i++ {
+			count += typeArgOccurrences(t.Field(i).Type, arg)
+		}
+		if count > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// typeArgOccurrences returns how many times the type argument arg is
+// replaced by its type parameter in a field of type t, see genericType.
+// Named types other than arg are generated on their own and not looked into.
+func typeArgOccurrences(t reflect.Type, arg string) int {
+	if qualifiedTypeName(t) == arg {
+		return 1
+	}
+	if isOption(t) || isPatch(t) {
+		value, _ := t.FieldByName("Value")
+		return typeArgOccurrences(value.Type, arg)
+	}
+	if isGenericType(t) {
+		return typeArgNameOccurrences(t.Name(), arg)
+	}
+	if t.Name() != "" {
+		return 0
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return typeArgOccurrences(t.Elem(), arg)
+	case reflect.Map:
+		return typeArgOccurrences(t.Key(), arg) + typeArgOccurrences(t.Elem(), arg)
+	case reflect.Struct:
+		count := 0
+		for i := 0; i < t.NumField(); i++ {
+			count += typeArgOccurrences(t.Field(i).Type, arg)
+		}
+		return count
+	}
+	return 0
+}
+
+// typeArgNameOccurrences returns how many times arg is a type argument of the
+// instantiated generic type name, or of the ones nested in its arguments.
+func typeArgNameOccurrences(name, arg string) int {
+	_, args := splitGenericName(name)
+	count := 0
+	for _, other := range args {
+		if other == arg {
+			count++
+		} else {
+			count += typeArgNameOccurrences(other, arg)
+		}
+	}
+	return count
+}
+
+// splitGenericName splits the name of an instantiated generic type into the
+// generic type name and its type arguments:
+//
+//	Pair[int,main.Page[main.User]] -> Pair, [int, main.Page[main.User]]
+func splitGenericName(name string) (string, []string) {
+	start := strings.Index(name, "[")
+	if start == -1 || !strings.HasSuffix(name, "]") {
+		return name, nil
+	}
+
+	var args []string
+	depth, argStart := 0, start+1
+	for i := argStart; i < len(name)-1; i++ {
+		switch name[i] {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, name[argStart:i])
+				argStart = i + 1
+			}
+		}
+	}
+	args = append(args, name[argStart:len(name)-1])
+	return name[:start], args
+}
+
+// qualifiedTypeName returns the name of t as written by reflect in the type
+// arguments of instantiated generic types.
+func qualifiedTypeName(t reflect.Type) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name()
+		}
+		return t.PkgPath() + "." + t.Name()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + qualifiedTypeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + qualifiedTypeName(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), qualifiedTypeName(t.Elem()))
+	case reflect.Map:
+		return "map[" + qualifiedTypeName(t.Key()) + "]" + qualifiedTypeName(t.Elem())
+	default:
+		return t.String()
 	}
-	pkgParts := strings.Split(t.PkgPath(), "/")
-	pkg := pkgParts[len(pkgParts)-1]
-	name := fmt.Sprintf("%s_%s", pkg, t.Name())
-	return name
 }
 
 func escapeFieldName(name string) string {
@@ -61,15 +306,579 @@ func escapeFieldName(name string) string {
 	return name
 }
 
+func isBrandableScalar(t reflect.Type) bool {
+	// Builtin types such as string or int have no package path, only types
+	// declared by the user can be branded.
+	if t.Name() == "" || t.PkgPath() == "" {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// int64Type returns the typescript type of 64-bit integers, see Int64Encoding
+func (tp *typegen) int64Type(t reflect.Type) string {
+	if !is64BitInt(t) {
+		return "number"
+	}
+	switch tp.int64Encoding {
+	case Int64AsString:
+		return "string"
+	case Int64AsBigInt:
+		return "bigint"
+	default:
+		return "number"
+	}
+}
+
+// brandedType registers a branded type for the given named scalar type,
+// along with a constructor helper of the same name so that values can be
+// created from the frontend:
+//
+//	export type main_UserID = string & { __brand: 'main_UserID' }
+//	export const main_UserID = (value: string): main_UserID => value as main_UserID
+func (tp *typegen) brandedType(t reflect.Type) string {
+	fullName := tp.typeName(t)
+	if _, exists := tp.typeDefs.Get(fullName); exists {
+		return fullName
+	}
+
+	underlying := "number"
+	if t.Kind() == reflect.String {
+		underlying = "string"
+	} else if is64BitInt(t) {
+		underlying = tp.int64Type(t)
+	}
+
+	tp.setTypeDef(fullName, tp.packageName(t), fmt.Sprintf(
+		"%[3]sexport type %[1]s = %[2]s & { __brand: '%[1]s' }\n"+
+			"export const %[1]s = (value: %[2]s): %[1]s => value as %[1]s",
+		fullName, underlying, tp.typeDoc(t)))
+	return fullName
+}
+
+// enumSet holds the allowed values of an enum type.
+type enumSet struct {
+	values  []any
+	allowed map[any]bool
+}
+
+func newEnumSet(values []any) *enumSet {
+	set := &enumSet{values: values, allowed: make(map[any]bool, len(values))}
+	for _, value := range values {
+		set.allowed[value] = true
+	}
+	return set
+}
+
+// isEnumKind tells whether values of t can be enum values.
+func isEnumKind(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// enumValues returns the allowed values of t, either registered with AddEnum
+// or returned by a `Values() []T` method declared on t. The values are
+// resolved once per type.
+func (tp *typegen) enumValues(t reflect.Type) ([]any, bool) {
+	set := tp.enumSet(t)
+	if set == nil {
+		return nil, false
+	}
+	return set.values, true
+}
+
+func (tp *typegen) enumSet(t reflect.Type) *enumSet {
+	if set, ok := tp.enums.Load(t); ok {
+		return set.(*enumSet)
+	}
+	set, _ := tp.enums.LoadOrStore(t, methodEnumSet(t))
+	return set.(*enumSet)
+}
+
+// methodEnumSet returns the values returned by the `Values() []T` method of
+// t, or nil if t has none.
+func methodEnumSet(t reflect.Type) *enumSet {
+	if t.Name() == "" || !isEnumKind(t) {
+		return nil
+	}
+	method, ok := t.MethodByName("Values")
+	if !ok {
+		return nil
+	}
+	// The receiver is the first argument of the method
+	if method.Type.NumIn() != 1 || method.Type.NumOut() != 1 {
+		return nil
+	}
+	out := method.Type.Out(0)
+	if out.Kind() != reflect.Slice || out.Elem() != t {
+		return nil
+	}
+
+	result := method.Func.Call([]reflect.Value{reflect.Zero(t)})[0]
+	values := make([]any, result.Len())
+	for i := range values {
+		values[i] = result.Index(i).Interface()
+	}
+	return newEnumSet(values)
+}
+
+// addEnum registers the allowed values of t, replacing the ones of its
+// `Values() []T` method if any.
+func (tp *typegen) addEnum(t reflect.Type, values []any) {
+	tp.enums.Store(t, newEnumSet(values))
+}
+
+// enumType registers a string literal union for the given enum type, along
+// with an array of all its values:
+//
+//	export type main_Status = "active" | "archived"
+//	export const main_StatusValues = ["active", "archived"] as const
+func (tp *typegen) enumType(t reflect.Type, values []any) string {
+	fullName := tp.typeName(t)
+	if _, exists := tp.typeDefs.Get(fullName); exists {
+		return fullName
+	}
+
+	literals := make([]string, 0, len(values))
+	for _, value := range values {
+		literal, err := json.Marshal(value)
+		if err != nil {
+			panic(fmt.Sprintf("cannot encode enum value %v of %s: %s", value, fullName, err))
+		}
+		literals = append(literals, string(literal))
+	}
+
+	tp.setTypeDef(fullName, tp.packageName(t), fmt.Sprintf(
+		"%[4]sexport type %[1]s = %[2]s\n"+
+			"export const %[1]sValues = [%[3]s] as const",
+		fullName, strings.Join(literals, " | "), strings.Join(literals, ", "), tp.typeDoc(t)))
+	return fullName
+}
+
+// validateEnums walks the given value and returns an error if any enum typed
+// value in it is not one of the allowed values.
+func (tp *typegen) validateEnums(v reflect.Value) error {
+	t := v.Type()
+	if set := tp.enumSet(t); set != nil {
+		value := v.Interface()
+		if !set.allowed[value] {
+			return fmt.Errorf("invalid value %v for %s", value, t)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return tp.validateEnums(v.Elem())
+	case reflect.Struct:
+		// Options that are not set hold the zero value, which does not need to
+		// be one of the allowed values
+		if v.CanAddr() {
+			if opt, ok := v.Addr().Interface().(interface{ Valid() bool }); ok && !opt.Valid() {
+				return nil
+			}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if err := tp.validateEnums(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := tp.validateEnums(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := tp.validateEnums(iter.Key()); err != nil {
+				return err
+			}
+			if err := tp.validateEnums(iter.Value()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// unionType registers a discriminated union for an interface registered with
+// AddUnion.
+func (tp *typegen) unionType(iface reflect.Type) string {
+	u, ok := tp.unions[iface]
+	if !ok {
+		return "any"
+	}
+
+	fullName := tp.viewName(iface)
+	if tp.processingTypes[fullName] {
+		return fullName
+	}
+	if _, exists := tp.typeDefs.Get(fullName); exists {
+		return fullName
+	}
+	tp.processingTypes[fullName] = true
+
+	var variants []string
+	for _, impl := range u.impls {
+		variants = append(variants, fmt.Sprintf("  | ({ %s: %q } & %s)",
+			escapeFieldName(u.tag), u.kindOf(impl), tp.FillTypeDefinitions(impl)))
+	}
+
+	delete(tp.processingTypes, fullName)
+
+	tp.setTypeDef(fullName, tp.packageName(iface), fmt.Sprintf("%sexport type %s =\n%s",
+		tp.typeDoc(iface), fullName, strings.Join(variants, "\n")))
+	return fullName
+}
+
+// structFields returns the typescript fields of a struct, one per line.
+func (tp *typegen) structFields(t reflect.Type) string {
+	typeName, _ := splitGenericName(t.Name())
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldName := jsonFieldName(field)
+		tag := parseTsTag(field)
+		if fieldName == "" || tag.exclude {
+			continue
+		}
+		doc := ""
+		if typeName != "" {
+			doc = tp.docs.lookup(t.PkgPath(), typeName, field.Name)
+		}
+		if note, deprecated := fieldDeprecation(field); deprecated {
+			doc = withDeprecation(doc, note)
+		}
+		fields = append(fields, jsDoc(doc, "  ")+tp.structField(escapeFieldName(fieldName), field, tag))
+	}
+	return strings.Join(fields, "\n")
+}
+
+// structField returns the typescript definition of a struct field, following
+// how encoding/json handles it:
+//
+//   - Responses always contain pointer, Option, Patch and Union fields, which
+//     are null if not set, unless they are omitempty pointers, which are
+//     omitted instead. Any other omitempty field may be omitted too.
+//   - Params may omit pointer, Option, Patch and Union fields, or send them
+//     as null.
+//
+// Both can be overridden with the ts tag, see parseTsTag. Fields with the
+// string option of encoding/json are strings, see isQuoted.
+func (tp *typegen) structField(name string, field reflect.StructField, tag tsTag) string {
+	fieldType := tag.typ
+	if fieldType == "" && isQuoted(field) {
+		fieldType = "string"
+	} else if fieldType == "" {
+		outerEncoding, outerNonNull := tp.int64Encoding, tp.nonNullCollections
+		tp.int64Encoding = fieldInt64Encoding(field, tp.defaultInt64Encoding)
+		tp.nonNullCollections = fieldNonNullCollections(field, tp.defaultNonNullCollections)
+		outerAnonName := tp.anonName
+		if tp.anonName != "" {
+			tp.anonName += "_" + field.Name
+		}
+		fieldType = tp.FillTypeDefinitions(field.Type)
+		tp.int64Encoding, tp.nonNullCollections = outerEncoding, outerNonNull
+		tp.anonName = outerAnonName
+	}
+
+	optional, nullable := fieldPresence(field, tag, tp.input)
+	if nullable && tag.typ == "" {
+		fieldType += " | null"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("  ")
+	if tag.readonly {
+		sb.WriteString("readonly ")
+	}
+	sb.WriteString(name)
+	if optional {
+		sb.WriteString("?")
+	}
+	sb.WriteString(": ")
+	sb.WriteString(fieldType)
+	return sb.String()
+}
+
+// fieldPresence tells whether a field may be omitted or null in params, if
+// input is set, or in responses, see structField.
+func fieldPresence(field reflect.StructField, tag tsTag, input bool) (optional, nullable bool) {
+	isPtr := field.Type.Kind() == reflect.Ptr
+	nullable = isPtr || isOption(field.Type) || isPatch(field.Type) || isUnion(field.Type)
+	omitempty := hasOmitempty(field)
+
+	switch {
+	case input:
+		optional = nullable
+	case isPtr && omitempty:
+		optional, nullable = true, false
+	case !nullable:
+		optional = omitempty
+	}
+
+	if tag.optional {
+		optional = true
+	} else if tag.required {
+		optional = false
+	}
+	return optional, nullable
+}
+
+// tsTag holds the options of the ts struct tag, which overrides how a field
+// is generated:
+//
+//	Meta     map[string]any `ts:"type=Record<string, unknown>"`
+//	Nickname string         `ts:"optional"`
+//	Avatar   *string        `ts:"required"`
+//	ID       string         `ts:"readonly"`
+//	Secret   string         `ts:"-"`
+//
+// Options can be combined, as in `ts:"type=string | number,readonly"`.
+type tsTag struct {
+	typ      string
+	optional bool
+	required bool
+	readonly bool
+	exclude  bool
+}
+
+func parseTsTag(field reflect.StructField) tsTag {
+	var tag tsTag
+	value := field.Tag.Get("ts")
+	if value == "-" {
+		tag.exclude = true
+		return tag
+	}
+
+	// Types may contain commas themselves, as in Record<string, unknown>
+	var opts []string
+	depth, start := 0, 0
+	for i, ch := range value {
+		switch ch {
+		case '<', '{', '(', '[':
+			depth++
+		case '>', '}', ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				opts = append(opts, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	opts = append(opts, value[start:])
+
+	for _, opt := range opts {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case strings.HasPrefix(opt, "type="):
+			tag.typ = strings.TrimPrefix(opt, "type=")
+		case opt == "optional":
+			tag.optional = true
+		case opt == "required":
+			tag.required = true
+		case opt == "readonly":
+			tag.readonly = true
+		}
+	}
+	return tag
+}
+
+// nullableCollection returns the type of a slice or map, which is null when nil
+// unless configured otherwise, see Config.NonNullCollections.
+func (tp *typegen) nullableCollection(collection string) string {
+	if tp.nonNullCollections {
+		return collection
+	}
+	return fmt.Sprintf("(%s | null)", collection)
+}
+
+// mapKeyType returns the typescript type of map keys, which encoding/json
+// always encodes as strings.
+func (tp *typegen) mapKeyType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		if _, ok := tp.enumValues(t); ok || tp.brandedTypes && isBrandableScalar(t) {
+			return tp.FillTypeDefinitions(t)
+		}
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// isByteSlice tells whether t is encoded by encoding/json as a base64 string,
+// which is the case of slices of bytes unless the bytes have their own
+// encoding.
+func isByteSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	ptr := reflect.PointerTo(t.Elem())
+	return !ptr.Implements(reflect.TypeFor[json.Marshaler]()) &&
+		!ptr.Implements(reflect.TypeFor[encoding.TextMarshaler]())
+}
+
+func isOption(t reflect.Type) bool {
+	return isForjaGeneric(t, "Option")
+}
+
+func isUnion(t reflect.Type) bool {
+	return isForjaGeneric(t, "Union")
+}
+
+func isPatch(t reflect.Type) bool {
+	return isForjaGeneric(t, "Patch")
+}
+
+func hasOmitempty(field reflect.StructField) bool {
+	return hasJSONOption(field, "omitempty")
+}
+
+// isQuoted tells whether encoding/json encodes the value of field within a
+// json string, as it does for scalar fields with the string option:
+//
+//	ID int64 `json:"id,string"`
+func isQuoted(field reflect.StructField) bool {
+	if !hasJSONOption(field, "string") {
+		return false
+	}
+	t := field.Type
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+func hasJSONOption(field reflect.StructField, option string) bool {
+	_, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// viewsDiffer tells whether the input and output views of t are different,
+// see structField.
+func (tp *typegen) viewsDiffer(t reflect.Type) bool {
+	return typeWalk[struct{}]{
+		unions: tp.union,
+		field: func(field reflect.StructField) (struct{}, fieldMatch) {
+			switch {
+			case parseTsTag(field).exclude:
+				return struct{}{}, fieldSkip
+			case field.Type.Kind() == reflect.Ptr || isOption(field.Type) || isPatch(field.Type) ||
+				isUnion(field.Type) || hasOmitempty(field):
+				return struct{}{}, fieldFound
+			default:
+				return struct{}{}, fieldWalk
+			}
+		},
+	}.find(t, struct{}{})
+}
+
+// union returns the union registered for iface with AddUnion.
+func (tp *typegen) union(iface reflect.Type) (*union, bool) {
+	u, ok := tp.unions[iface]
+	return u, ok
+}
+
+// viewName returns the name of the current view of the named type t.
+func (tp *typegen) viewName(t reflect.Type) string {
+	fullName := tp.typeName(t)
+	if tp.input && tp.viewsDiffer(t) {
+		return fullName + "Input"
+	}
+	return fullName
+}
+
+// InputType returns the typescript type of t as sent by the client. If t is
+// an anonymous struct, it is generated with the given name in package pkg.
+func (tp *typegen) InputType(t reflect.Type, pkg, name string) string {
+	tp.input, tp.anonName, tp.anonInNamed, tp.anonPackage = true, name, false, pkg
+	defer func() { tp.input, tp.anonName = false, "" }()
+	return tp.FillTypeDefinitions(t)
+}
+
+// OutputType returns the typescript type of t as sent by the server. If t is
+// an anonymous struct, it is generated with the given name in package pkg.
+func (tp *typegen) OutputType(t reflect.Type, pkg, name string) string {
+	tp.anonName, tp.anonInNamed, tp.anonPackage = name, false, pkg
+	defer func() { tp.anonName = "" }()
+	return tp.FillTypeDefinitions(t)
+}
+
 func (tp *typegen) FillTypeDefinitions(t reflect.Type) string {
+	// While generating the body of a generic type, its type arguments are
+	// replaced by the type parameters.
+	if len(tp.typeParams) > 0 {
+		if param, ok := tp.typeParams[qualifiedTypeName(t)]; ok {
+			tp.typeArgs[param] = t
+			return param
+		}
+	}
+
+	if values, ok := tp.enumValues(t); ok {
+		return tp.enumType(t, values)
+	}
+
+	if tp.durationAsMilliseconds && isDuration(t) {
+		return "number"
+	}
+
+	if tp.brandedTypes && isBrandableScalar(t) {
+		return tp.brandedType(t)
+	}
+
 	switch t.Kind() {
 	case reflect.Struct:
-		fullName := getFullTypeName(t)
-		if t.PkgPath() == "time" && t.Name() == "Time" {
+		fullName := tp.typeName(t)
+		if isTime(t) {
+			if tp.timeAsDate {
+				return "Date"
+			}
 			return "string"
 		}
 
-		if strings.Contains(fullName, "forja_Option") {
+		if isOption(t) || isPatch(t) {
 			for i := 0; i < t.NumField(); i++ {
 				field := t.Field(i)
 				if field.Name == "Value" {
@@ -78,7 +887,17 @@ func (tp *typegen) FillTypeDefinitions(t reflect.Type) string {
 			}
 		}
 
+		if isUnion(t) {
+			return tp.unionType(t.Field(0).Type)
+		}
+
+		if isGenericType(t) && !isAmbiguousInstance(t) {
+			return tp.genericType(t)
+		}
+
 		if fullName != "" {
+			fullName = tp.viewName(t)
+
 			// Check if we're already processing this type (circular reference)
 			if tp.processingTypes[fullName] {
 				return fullName // Just return the type name for circular references
@@ -91,61 +910,52 @@ func (tp *typegen) FillTypeDefinitions(t reflect.Type) string {
 			// Mark this type as being processed
 			tp.processingTypes[fullName] = true
 
-			var fields []string
-			for i := 0; i < t.NumField(); i++ {
-				field := t.Field(i)
-				fieldName := field.Name
-				jsonTag := field.Tag.Get("json")
-				if jsonTag != "" {
-					fieldName = strings.Split(jsonTag, ",")[0]
-				}
-				fieldName = escapeFieldName(fieldName)
-
-				fieldType := tp.FillTypeDefinitions(field.Type)
-				if field.Type.Kind() == reflect.Ptr {
-					fields = append(fields, fmt.Sprintf("  %s?: %s", fieldName, fieldType))
-				} else if strings.Contains(getFullTypeName(field.Type), "forja_Option") {
-					fields = append(fields, fmt.Sprintf("  %s?: %s", fieldName, fieldType))
-				} else {
-					fields = append(fields, fmt.Sprintf("  %s: %s", fieldName, fieldType))
-				}
-
-			}
+			// Named types never depend on the type parameters of the generic
+			// type that uses them
+			outerParams, outerAnonName, outerAnonInNamed, outerAnonPackage := tp.typeParams, tp.anonName, tp.anonInNamed, tp.anonPackage
+			tp.typeParams, tp.anonName, tp.anonInNamed, tp.anonPackage = nil, tp.typeName(t), true, tp.packageName(t)
+			fields := tp.structFields(t)
+			tp.typeParams, tp.anonName, tp.anonInNamed, tp.anonPackage = outerParams, outerAnonName, outerAnonInNamed, outerAnonPackage
 
 			// Remove from processing map after we're done
 			delete(tp.processingTypes, fullName)
 
-			tp.typeDefs.Set(fullName, fmt.Sprintf("export type %s = {\n%s\n}", fullName, strings.Join(fields, "\n")))
+			tp.setTypeDef(fullName, tp.packageName(t), fmt.Sprintf("%sexport type %s = {\n%s\n}", tp.typeDoc(t), fullName, fields))
 			return fullName
 		}
 
-		// For anonymous structs, inline the definition
-		var fields []string
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-			jsonTag := field.Tag.Get("json")
-			if jsonTag == "" {
-				jsonTag = strings.ToLower(field.Name)
-			} else {
-				jsonTag = strings.Split(jsonTag, ",")[0]
-			}
-			jsonTag = escapeFieldName(jsonTag)
-			fieldType := tp.FillTypeDefinitions(field.Type)
-			optional := ""
-			if field.Type.Kind() == reflect.Ptr {
-				fieldType = tp.FillTypeDefinitions(field.Type.Elem())
-				optional = "?"
-			}
-			fields = append(fields, fmt.Sprintf("  %s%s: %s", jsonTag, optional, fieldType))
-		}
-		return fmt.Sprintf("{\n%s\n}", strings.Join(fields, "\n"))
+		return tp.hoistedType(t)
 
 	case reflect.Slice:
-		return fmt.Sprintf("(%s[] | null)", tp.FillTypeDefinitions(t.Elem()))
+		if t == reflect.TypeFor[json.RawMessage]() {
+			return "unknown"
+		}
+		if isByteSlice(t) {
+			tp.bytesUsed = true
+			return tp.nullableCollection("string")
+		}
+		return tp.nullableCollection(tp.FillTypeDefinitions(t.Elem()) + "[]")
+	case reflect.Map:
+		record := fmt.Sprintf("Record<%s, %s>", tp.mapKeyType(t.Key()), tp.FillTypeDefinitions(t.Elem()))
+		// Maps keyed by an enum do not need to contain every value
+		if _, ok := tp.enumValues(t.Key()); ok {
+			record = fmt.Sprintf("Partial<%s>", record)
+		}
+		return tp.nullableCollection(record)
+	case reflect.Array:
+		// encoding/json encodes arrays as json arrays, even byte arrays
+		elem := tp.FillTypeDefinitions(t.Elem())
+		items := make([]string, t.Len())
+		for i := range items {
+			items[i] = elem
+		}
+		return "[" + strings.Join(items, ", ") + "]"
 	case reflect.String:
 		return "string"
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+	case reflect.Int64, reflect.Uint64:
+		return tp.int64Type(t)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
 		reflect.Float32, reflect.Float64:
 		return "number"
 	case reflect.Bool:
@@ -157,6 +967,122 @@ func (tp *typegen) FillTypeDefinitions(t reflect.Type) string {
 	}
 }
 
+// hoistedType registers an anonymous struct as a named type, so that the
+// frontend can refer to it. Its name is derived from the named type or handler
+// that contains it, followed by the fields that lead to it:
+//
+//	type PointersAreUndefined struct {
+//		AnotherPtr *struct{ Name string }
+//	}
+//
+// generates main_PointersAreUndefined_AnotherPtr, or
+// main_PointersAreUndefined_AnotherPtrInput for its input view if it differs.
+//
+// Empty structs and anonymous structs within generic types, which may depend
+// on their type parameters, are inlined instead.
+func (tp *typegen) hoistedType(t reflect.Type) string {
+	if t.NumField() == 0 {
+		return "{}"
+	}
+	if tp.anonName == "" || len(tp.typeParams) > 0 {
+		return fmt.Sprintf("{\n%s\n}", tp.structFields(t))
+	}
+
+	baseName := tp.anonName
+	if tp.input && tp.anonInNamed && tp.viewsDiffer(t) {
+		baseName += "Input"
+	}
+
+	// Different anonymous structs may end up with the same name, such as the
+	// params of two handlers registered under the same name.
+	name := baseName
+	for i := 2; tp.anonTypes[name] != nil && tp.anonTypes[name] != t; i++ {
+		name = fmt.Sprintf("%s%d", baseName, i)
+	}
+	if _, exists := tp.typeDefs.Get(name); exists {
+		return name
+	}
+	tp.anonTypes[name] = t
+
+	outerAnonName := tp.anonName
+	tp.anonName = name
+	tp.setTypeDef(name, tp.anonPackage, fmt.Sprintf("export type %s = {\n%s\n}", name, tp.structFields(t)))
+	tp.anonName = outerAnonName
+	return name
+}
+
+// genericType registers a generic type definition for an instantiated generic
+// struct and returns its instantiation, so that Page[User] is generated as
+//
+//	export type main_Page<T> = {
+//	  items: (T[] | null)
+//	}
+//
+// and used as main_Page<main_User>.
+//
+// reflect does not know about type parameters, so the definition is derived
+// from the instantiation by replacing every occurrence of a type argument by
+// its parameter. That is ambiguous when a type argument is a builtin type or
+// appears twice, as in Page[int] with a `Total int` field or Owned[User] with
+// an `Owner User` field, so such instantiations are instead generated as a
+// concrete type each, main_Page_int.
+func (tp *typegen) genericType(t reflect.Type) string {
+	fullName := tp.viewName(t)
+	_, args := splitGenericName(t.Name())
+
+	params := []string{"T"}
+	if len(args) > 1 {
+		params = make([]string, len(args))
+		for i := range args {
+			params[i] = fmt.Sprintf("T%d", i+1)
+		}
+	}
+
+	instance := fullName + " " + qualifiedTypeName(t)
+	argTypes, walked := tp.genericArgTypes[instance]
+	if !walked && !tp.processingTypes[fullName] {
+		typeParams := make(map[string]string)
+		for i, arg := range args {
+			typeParams[arg] = params[i]
+		}
+
+		outerParams, outerArgs := tp.typeParams, tp.typeArgs
+		tp.typeParams, tp.typeArgs = typeParams, make(map[string]reflect.Type)
+		tp.processingTypes[fullName] = true
+
+		fields := tp.structFields(t)
+
+		delete(tp.processingTypes, fullName)
+		found := tp.typeArgs
+		tp.typeParams, tp.typeArgs = outerParams, outerArgs
+
+		if _, defined := tp.typeDefs.Get(fullName); !defined {
+			tp.setTypeDef(fullName, tp.packageName(t), fmt.Sprintf("%sexport type %s<%s> = {\n%s\n}",
+				tp.typeDoc(t), fullName, strings.Join(params, ", "), fields))
+		}
+
+		argTypes = make([]reflect.Type, len(args))
+		for i, arg := range args {
+			argTypes[i] = found[typeParams[arg]]
+		}
+		tp.genericArgTypes[instance] = argTypes
+	}
+
+	tsArgs := make([]string, len(args))
+	for i, arg := range args {
+		if param, ok := tp.typeParams[arg]; ok {
+			tsArgs[i] = param
+		} else if i < len(argTypes) && argTypes[i] != nil {
+			tsArgs[i] = tp.FillTypeDefinitions(argTypes[i])
+		} else {
+			// The type argument is not used by any field
+			tsArgs[i] = "unknown"
+		}
+	}
+
+	return fmt.Sprintf("%s<%s>", fullName, strings.Join(tsArgs, ", "))
+}
+
 func (tp *typegen) generateTypeDefinition(t reflect.Type) string {
 	typename := tp.FillTypeDefinitions(t)
 
@@ -166,7 +1092,31 @@ func (tp *typegen) generateTypeDefinition(t reflect.Type) string {
 		panic("Anonymous type not supported")
 	}
 
+	// Generic types are defined once for all their instantiations
+	typename, _, _ = strings.Cut(typename, "<")
+
 	typedef, _ := tp.typeDefs.Get(typename)
 	fmt.Fprintln(&sb, typedef)
 	return sb.String()
 }
+
+const base64Helpers = `
+// decodeBase64 decodes the base64 strings that []byte values are sent as
+export function decodeBase64(value: string): Uint8Array {
+  const binary = atob(value)
+  const bytes = new Uint8Array(binary.length)
+  for (let i = 0; i < binary.length; i++) {
+    bytes[i] = binary.charCodeAt(i)
+  }
+  return bytes
+}
+
+// encodeBase64 encodes bytes to be sent as a []byte value
+export function encodeBase64(bytes: Uint8Array): string {
+  let binary = ''
+  for (let i = 0; i < bytes.length; i++) {
+    binary += String.fromCharCode(bytes[i])
+  }
+  return btoa(binary)
+}
+`
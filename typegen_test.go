@@ -0,0 +1,149 @@
+package forja
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type genericUser struct {
+	Name string `json:"name"`
+}
+
+type genericOrg struct {
+	Title string `json:"title"`
+}
+
+type genericOwned[T any] struct {
+	Val   T           `json:"val"`
+	Owner genericUser `json:"owner"`
+}
+
+func getOwnedUser(c echo.Context, params genericOrg) (genericOwned[genericUser], error) {
+	return genericOwned[genericUser]{}, nil
+}
+
+func getOwnedOrg(c echo.Context, params genericOrg) (genericOwned[genericOrg], error) {
+	return genericOwned[genericOrg]{}, nil
+}
+
+func TestGenericTypeArgumentInFields(t *testing.T) {
+	fj := NewForja(echo.New())
+	// The instance whose type argument is also the type of another field is
+	// registered first, so that it would be the one the definition comes from
+	AddHandler(fj, getOwnedUser)
+	AddHandler(fj, getOwnedOrg)
+	client := fj.GenerateTypescriptClient()
+
+	for _, want := range []string{
+		"export type forja_genericOwned_forja_genericUser = {\n  val: forja_genericUser\n  owner: forja_genericUser\n}",
+		"export type forja_genericOwned<T> = {\n  val: T\n  owner: forja_genericUser\n}",
+		"Promise<ApiResponse<forja_genericOwned_forja_genericUser>>",
+		"Promise<ApiResponse<forja_genericOwned<forja_genericOrg>>>",
+	} {
+		if !strings.Contains(client, want) {
+			t.Errorf("generated client does not contain\n%s\ngot\n%s", want, client)
+		}
+	}
+}
+
+type unionShape interface{ isUnionShape() }
+
+type unionCircle struct {
+	Radius float64 `json:"radius"`
+}
+
+func (unionCircle) isUnionShape() {}
+
+type unionDrawing struct {
+	Shape Union[unionShape] `json:"shape"`
+}
+
+func drawUnion(c echo.Context, params unionDrawing) (unionDrawing, error) {
+	return unionDrawing{}, nil
+}
+
+func TestUnionFieldNullable(t *testing.T) {
+	fj := NewForja(echo.New())
+	AddUnion[unionShape](fj, "kind", unionCircle{})
+	AddHandler(fj, drawUnion)
+	client := fj.GenerateTypescriptClient()
+
+	// A zero Union is encoded as null
+	for _, want := range []string{
+		"export type forja_unionDrawing = {\n  shape: forja_unionShape | null\n}",
+		"export type forja_unionDrawingInput = {\n  shape?: forja_unionShape | null\n}",
+	} {
+		if !strings.Contains(client, want) {
+			t.Errorf("generated client does not contain\n%s\ngot\n%s", want, client)
+		}
+	}
+
+	snapshot, err := fj.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s schema
+	if err := json.Unmarshal(snapshot, &s); err != nil {
+		t.Fatal(err)
+	}
+	if field := s.Results["forja_unionDrawing"].Fields["shape"]; !field.Nullable {
+		t.Errorf("shape is not nullable in the results of the snapshot: %+v", field)
+	}
+}
+
+type enumStatus string
+
+type enumPriority int
+
+// Values registers enumPriority without AddEnum
+func (enumPriority) Values() []enumPriority {
+	return []enumPriority{1, 2, 3}
+}
+
+type enumParams struct {
+	Status     enumStatus              `json:"status"`
+	Priority   *enumPriority           `json:"priority"`
+	History    []enumStatus            `json:"history"`
+	ByStatus   map[enumStatus]int      `json:"byStatus"`
+	Previous   Option[enumStatus]      `json:"previous"`
+	Priorities map[string]enumPriority `json:"priorities"`
+}
+
+func setEnumStatus(c echo.Context, params enumParams) (watchCountResult, error) {
+	return watchCountResult{}, nil
+}
+
+func TestValidateEnums(t *testing.T) {
+	e := echo.New()
+	fj := NewForja(e)
+	AddEnum(fj, enumStatus("active"), enumStatus("archived"))
+	AddHandler(fj, setEnumStatus)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"valid", `{"status":"active","priority":2,"history":["archived"],"byStatus":{"active":1},"priorities":{"a":3}}`, 200},
+		{"unset option", `{"status":"active"}`, 200},
+		{"set option", `{"status":"active","previous":"archived"}`, 200},
+		{"invalid value", `{"status":"deleted"}`, 400},
+		{"zero value", `{}`, 400},
+		{"invalid pointer", `{"status":"active","priority":4}`, 400},
+		{"invalid slice item", `{"status":"active","history":["active","deleted"]}`, 400},
+		{"invalid map key", `{"status":"active","byStatus":{"deleted":1}}`, 400},
+		{"invalid map value", `{"status":"active","priorities":{"a":0}}`, 400},
+		{"invalid option", `{"status":"active","previous":"deleted"}`, 400},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, body := postJSON(t, e, "/forja.setEnumStatus", test.body)
+			if status != test.status {
+				t.Errorf("got status %d, want %d: %s", status, test.status, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package forja
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// union describes an interface registered with AddUnion
+type union struct {
+	// tag is the name of the discriminator field, "kind" for example
+	tag   string
+	impls []reflect.Type
+}
+
+// kindOf returns the discriminator value of the given implementation, which is
+// the name of its type.
+func (u *union) kindOf(impl reflect.Type) string {
+	if impl.Kind() == reflect.Ptr {
+		impl = impl.Elem()
+	}
+	return impl.Name()
+}
+
+// Unions are looked up by Union values while marshaling, which have no access
+// to the Forja instance, so they are also registered globally. Forja instances
+// registering the same interface must register the same union, see
+// registerUnion.
+var unions = struct {
+	sync.RWMutex
+	byType map[reflect.Type]*union
+}{byType: make(map[reflect.Type]*union)}
+
+// registerUnion registers u globally for iface, unless a different union is
+// already registered for it.
+func registerUnion(iface reflect.Type, u *union) error {
+	unions.Lock()
+	defer unions.Unlock()
+	if existing, ok := unions.byType[iface]; ok && !existing.equal(u) {
+		return fmt.Errorf("%s is already registered with other implementations or tag", iface)
+	}
+	unions.byType[iface] = u
+	return nil
+}
+
+func (u *union) equal(other *union) bool {
+	if u.tag != other.tag || len(u.impls) != len(other.impls) {
+		return false
+	}
+	for i, impl := range u.impls {
+		if other.impls[i] != impl {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupUnion(iface reflect.Type) (*union, bool) {
+	unions.RLock()
+	defer unions.RUnlock()
+	u, ok := unions.byType[iface]
+	return u, ok
+}
+
+// AddUnion registers the implementations of the interface I, so that
+// Union[I] values are encoded as a discriminated union on the tag field, whose
+// value is the name of the implementation type. Implementations must thus be
+// named, non generic types with distinct names.
+//
+//	forja.AddUnion[Shape](fj, "kind", Circle{}, Square{})
+//
+// generates
+//
+//	export type main_Shape =
+//	  | ({ kind: "Circle" } & main_Circle)
+//	  | ({ kind: "Square" } & main_Square)
+func AddUnion[I any](fj *Forja, tag string, impls ...I) {
+	iface := reflect.TypeFor[I]()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("AddUnion: %s is not an interface", iface))
+	}
+
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+
+	u := &union{tag: tag}
+	kinds := make(map[string]reflect.Type)
+	for _, impl := range impls {
+		t := reflect.TypeOf(impl)
+		if t == nil {
+			panic(fmt.Sprintf("AddUnion: nil implementation of %s", iface))
+		}
+		kind := u.kindOf(t)
+		if kind == "" || strings.Contains(kind, "[") {
+			panic(fmt.Sprintf("AddUnion: %s needs a named, non generic type to be part of union %s", t, iface))
+		}
+		if other, ok := kinds[kind]; ok {
+			panic(fmt.Sprintf("AddUnion: %s and %s have the same %s %q in union %s", other, t, tag, kind, iface))
+		}
+		kinds[kind] = t
+		u.impls = append(u.impls, t)
+	}
+
+	if err := registerUnion(iface, u); err != nil {
+		panic(fmt.Sprintf("AddUnion: %s", err))
+	}
+	fj.typegen.unions[iface] = u
+	// The rewrites of the handlers using the union are computed again
+	for pair := fj.handlers.Oldest(); pair != nil; pair = pair.Next() {
+		pair.Value.rewrites.Store(nil)
+	}
+	fj.changed()
+	if err := fj.typegen.checkTypeNames(iface, make(map[reflect.Type]bool)); err != nil {
+		panic(fmt.Sprintf("AddUnion: %s", err))
+	}
+}
+
+// Union holds a value of one of the implementations registered for I with
+// AddUnion. Use it instead of I in params and results, so that the value can
+// be bound from and encoded to json with its discriminator field.
+type Union[I any] struct {
+	Value I
+}
+
+func (u Union[I]) MarshalJSON() ([]byte, error) {
+	value := reflect.ValueOf(&u.Value).Elem()
+	if value.IsNil() {
+		return []byte("null"), nil
+	}
+
+	iface := value.Type()
+	def, ok := lookupUnion(iface)
+	if !ok {
+		return nil, fmt.Errorf("union %s is not registered", iface)
+	}
+
+	impl := value.Elem().Type()
+	known := false
+	for _, t := range def.impls {
+		if t == impl {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return nil, fmt.Errorf("%s is not registered as part of union %s", impl, iface)
+	}
+
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("%s must be encoded as a json object to be part of union %s", impl, iface)
+	}
+
+	tag, _ := json.Marshal(def.tag)
+	kind, _ := json.Marshal(def.kindOf(impl))
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	buf.Write(tag)
+	buf.WriteByte(':')
+	buf.Write(kind)
+	if !bytes.Equal(data, []byte("{}")) {
+		buf.WriteByte(',')
+	}
+	buf.Write(data[1:])
+	return buf.Bytes(), nil
+}
+
+func (u *Union[I]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		var zero I
+		u.Value = zero
+		return nil
+	}
+
+	iface := reflect.TypeFor[I]()
+	def, ok := lookupUnion(iface)
+	if !ok {
+		return fmt.Errorf("union %s is not registered", iface)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	rawKind, ok := fields[def.tag]
+	if !ok {
+		return fmt.Errorf("missing %q field for union %s", def.tag, iface)
+	}
+	var kind string
+	if err := json.Unmarshal(rawKind, &kind); err != nil {
+		return fmt.Errorf("invalid %q field for union %s: %w", def.tag, iface, err)
+	}
+
+	for _, impl := range def.impls {
+		if def.kindOf(impl) != kind {
+			continue
+		}
+
+		elem := impl
+		if impl.Kind() == reflect.Ptr {
+			elem = impl.Elem()
+		}
+		ptr := reflect.New(elem)
+		if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+			return err
+		}
+		if impl.Kind() == reflect.Ptr {
+			u.Value = ptr.Interface().(I)
+		} else {
+			u.Value = ptr.Elem().Interface().(I)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown %s %q for union %s", def.tag, kind, iface)
+}
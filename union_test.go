@@ -0,0 +1,91 @@
+package forja
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type unionPet interface{ isUnionPet() }
+
+type unionCat struct {
+	Lives int `json:"lives"`
+}
+
+func (unionCat) isUnionPet() {}
+
+type unionDog struct {
+	Good bool `json:"good"`
+}
+
+func (*unionDog) isUnionPet() {}
+
+type unionEmpty struct{}
+
+func (unionEmpty) isUnionPet() {}
+
+// unionFish is not registered
+type unionFish struct{}
+
+func (unionFish) isUnionPet() {}
+
+type unionOwner struct {
+	Pet  Union[unionPet]   `json:"pet"`
+	Pets []Union[unionPet] `json:"pets"`
+}
+
+func TestUnionRoundTrip(t *testing.T) {
+	AddUnion[unionPet](NewForja(echo.New()), "kind", unionCat{}, &unionDog{}, unionEmpty{})
+
+	owner := unionOwner{
+		Pet: Union[unionPet]{Value: &unionDog{Good: true}},
+		Pets: []Union[unionPet]{
+			{Value: unionCat{Lives: 9}},
+			{Value: unionEmpty{}},
+			{},
+		},
+	}
+	data, err := json.Marshal(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"pet":{"kind":"unionDog","good":true},"pets":[{"kind":"unionCat","lives":9},{"kind":"unionEmpty"},null]}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	var decoded unionOwner
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, owner) {
+		t.Errorf("got %+v, want %+v", decoded, owner)
+	}
+}
+
+func TestUnionErrors(t *testing.T) {
+	AddUnion[unionPet](NewForja(echo.New()), "kind", unionCat{}, &unionDog{}, unionEmpty{})
+
+	for _, test := range []struct {
+		body string
+		want string
+	}{
+		{`{"pet":{"lives":9}}`, `missing "kind" field`},
+		{`{"pet":{"kind":"unionBird"}}`, `unknown kind "unionBird"`},
+		{`{"pet":{"kind":1}}`, `invalid "kind" field`},
+	} {
+		var owner unionOwner
+		err := json.Unmarshal([]byte(test.body), &owner)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.body, err, test.want)
+		}
+	}
+
+	_, err := json.Marshal(Union[unionPet]{Value: unionFish{}})
+	if err == nil || !strings.Contains(err.Error(), "is not registered as part of union") {
+		t.Errorf("got error %v for an unregistered implementation", err)
+	}
+}
@@ -0,0 +1,84 @@
+package forja
+
+import "reflect"
+
+// typeWalk looks for something in the types of the values encoding/json
+// encodes for a Go type: the elements of pointers, slices, arrays and maps,
+// the value of Option, Patch and Union, the implementations of unions and the
+// encoded fields of structs. Struct fields pass a context of type C down to
+// the types they contain, such as their Int64Encoding.
+type typeWalk[C any] struct {
+	// unions returns the union registered for an interface
+	unions func(iface reflect.Type) (*union, bool)
+	// field returns the context of a struct field, and whether it is found,
+	// skipped or walked.
+	field func(field reflect.StructField) (C, fieldMatch)
+	// match tells whether t, contained by a field with context ctx, is found.
+	match func(t reflect.Type, ctx C) bool
+}
+
+type fieldMatch int
+
+const (
+	// fieldWalk walks the type of the field
+	fieldWalk fieldMatch = iota
+	// fieldSkip does not walk the type of the field
+	fieldSkip
+	// fieldFound stops the walk, the field is what is looked for
+	fieldFound
+)
+
+// find tells whether values of t, with context ctx, contain what is looked
+// for.
+func (w typeWalk[C]) find(t reflect.Type, ctx C) bool {
+	return w.walk(t, ctx, make(map[reflect.Type]bool))
+}
+
+func (w typeWalk[C]) walk(t reflect.Type, ctx C, visited map[reflect.Type]bool) bool {
+	if w.match != nil && w.match(t, ctx) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return w.walk(t.Elem(), ctx, visited)
+	case reflect.Interface:
+		if u, ok := w.unions(t); ok {
+			for _, impl := range u.impls {
+				if w.walk(impl, ctx, visited) {
+					return true
+				}
+			}
+		}
+		return false
+	case reflect.Struct:
+		if isOption(t) || isPatch(t) || isUnion(t) {
+			value, _ := t.FieldByName("Value")
+			return w.walk(value.Type, ctx, visited)
+		}
+		// The context of the fields of a struct does not depend on where the
+		// struct is found, so it is walked once
+		if visited[t] {
+			return false
+		}
+		visited[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if jsonFieldName(field) == "" {
+				continue
+			}
+			fieldCtx, match := w.field(field)
+			switch match {
+			case fieldFound:
+				return true
+			case fieldWalk:
+				if w.walk(field.Type, fieldCtx, visited) {
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
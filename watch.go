@@ -0,0 +1,163 @@
+package forja
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// watchInterval is how often WatchTsClient checks the written client.
+	watchInterval = time.Second
+	// watchDelay is how long WatchTsClient waits for registrations to stop
+	// before generating the client again.
+	watchDelay = 100 * time.Millisecond
+)
+
+// writeFileIfChanged atomically replaces the file at path with content, unless
+// it already has it, so that tools watching the file, such as the dev server
+// of the frontend, are not triggered for nothing and never read it half
+// written. It tells whether the file was written.
+func writeFileIfChanged(path string, content []byte) (bool, error) {
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, content) {
+		return false, nil
+	}
+
+	// The temporary file is created next to the destination, as renames
+	// across file systems are not atomic
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// clientWatcher keeps a client written by WatchTsClient up to date. Its
+// goroutine does all the generations and writes.
+type clientWatcher struct {
+	fj   *Forja
+	path string
+	done chan struct{}
+	// changes is notified by registrations, see changed
+	changes chan struct{}
+	// content is the last generated client
+	content []byte
+}
+
+// WatchTsClient writes the client to path like WriteTsClient, and keeps it up
+// to date until stop is called, for development:
+//
+//   - handlers, types and variables added afterwards, such as by plugins
+//     registered after startup, regenerate it once they stop being added
+//   - the file is checked every second, and written again if it no longer
+//     has the generated content, such as after checking out another branch
+//
+// The client is always generated as by WriteTsClient after the last
+// registration, whatever was registered in between. Tools rebuilding and
+// restarting the program when its sources change, such as air, get the
+// client written again by the new build only if it changed, so that the
+// frontend is not reloaded and type-checked for nothing.
+//
+// Errors writing the client after the first time are logged.
+func (fj *Forja) WatchTsClient(path string) (stop func(), err error) {
+	w := &clientWatcher{fj: fj, path: path, done: make(chan struct{}), changes: make(chan struct{}, 1)}
+
+	// Registrations after the first generation notify the watcher
+	fj.mu.Lock()
+	w.content = []byte(fj.generateTypescriptClient())
+	fj.watchers = append(fj.watchers, w)
+	fj.mu.Unlock()
+
+	if err := w.write(); err != nil {
+		fj.removeWatcher(w)
+		return nil, err
+	}
+
+	go w.run()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			fj.removeWatcher(w)
+			close(w.done)
+		})
+	}, nil
+}
+
+func (w *clientWatcher) run() {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	// settle fires once no registration happened for watchDelay
+	var settle <-chan time.Time
+	for {
+		var err error
+		select {
+		case <-w.done:
+			return
+		case <-w.changes:
+			settle = time.After(watchDelay)
+		case <-settle:
+			settle = nil
+			w.content = []byte(w.fj.GenerateTypescriptClient())
+			err = w.write()
+		case <-ticker.C:
+			err = w.write()
+		}
+		if err != nil {
+			log.Printf("forja: %s", err)
+		}
+	}
+}
+
+func (w *clientWatcher) write() error {
+	if _, err := writeFileIfChanged(w.path, w.content); err != nil {
+		return fmt.Errorf("failed to write TypeScript client to %s: %w", w.path, err)
+	}
+	return nil
+}
+
+func (fj *Forja) removeWatcher(w *clientWatcher) {
+	fj.mu.Lock()
+	defer fj.mu.Unlock()
+	for i, other := range fj.watchers {
+		if other == w {
+			fj.watchers = append(fj.watchers[:i], fj.watchers[i+1:]...)
+			return
+		}
+	}
+}
+
+// changed must be called with fj.mu held whenever a registration changes the
+// generated client. The clients being watched are generated again once
+// registrations settle, so that registering many handlers writes them once.
+func (fj *Forja) changed() {
+	fj.resetSchemaHash()
+	for _, w := range fj.watchers {
+		select {
+		case w.changes <- struct{}{}:
+		default:
+			// A change is already pending
+		}
+	}
+}
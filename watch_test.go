@@ -0,0 +1,92 @@
+package forja
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type watchAddress struct {
+	Street *string `json:"street"`
+}
+
+type watchUser struct {
+	Name    string        `json:"name"`
+	Address *watchAddress `json:"address"`
+}
+
+type watchGetParams struct {
+	ID string `json:"id"`
+}
+
+type watchListResult struct {
+	Users []watchUser `json:"users"`
+}
+
+type watchCountResult struct {
+	Count int `json:"count"`
+}
+
+func watchGet(c echo.Context, params watchGetParams) (watchUser, error) {
+	return watchUser{}, nil
+}
+
+func watchList(c echo.Context, params watchAddress) (watchListResult, error) {
+	return watchListResult{}, nil
+}
+
+func watchCount(c echo.Context, params watchGetParams) (watchCountResult, error) {
+	return watchCountResult{}, nil
+}
+
+// registerWatched registers handlers whose types are generated in a
+// different order than they are registered, as handlers are grouped by
+// namespace, calling between after every registration.
+func registerWatched(fj *Forja, between func()) {
+	AddHandler(fj, watchGet)
+	between()
+	AddHandlerWith(fj, watchList, Namespace("other"))
+	between()
+	AddHandler(fj, watchCount)
+	between()
+	fj.AddVariable("limit", 10)
+}
+
+func TestWatchTsClientLateRegistration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.ts")
+	fj := NewForja(echo.New())
+	stop, err := fj.WatchTsClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// Generations in between must not change the order of the types
+	registerWatched(fj, func() { fj.GenerateTypescriptClient() })
+
+	fresh := NewForja(echo.New())
+	registerWatched(fresh, func() {})
+	want := fresh.GenerateTypescriptClient()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) == want {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("watched client differs from a fresh generation, got\n%s\nwant\n%s", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := fj.GenerateTypescriptClient(); got != want {
+		t.Errorf("generated client differs from a fresh generation, got\n%s\nwant\n%s", got, want)
+	}
+}